@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CacheStats counts run-task actions that were restored from the cache
+// against those that executed. Skipped tasks count as neither.
+type CacheStats struct {
+	Hits       int
+	RemoteHits int
+	Misses     int
+}
+
+func (s CacheStats) total() int {
+	return s.Hits + s.RemoteHits + s.Misses
+}
+
+// HitRate is the percentage of tasks served from the local or remote cache.
+func (s CacheStats) HitRate() float64 {
+	if s.total() == 0 {
+		return 0
+	}
+	return float64(s.Hits+s.RemoteHits) * 100 / float64(s.total())
+}
+
+func (s *CacheStats) add(status string) {
+	switch {
+	case status == "cached":
+		s.Hits++
+	case status == "cached-from-remote":
+		s.RemoteHits++
+	case status == "passed" || isFailedStatus(status):
+		s.Misses++
+	}
+}
+
+// CacheReport is the cache section of the action's output. UncachedUnchanged
+// lists targets that executed although their hash matches the previous run,
+// meaning moon could have restored them.
+type CacheReport struct {
+	Overall           CacheStats
+	Projects          map[string]*CacheStats
+	UncachedUnchanged []string
+	HasBaseline       bool
+}
+
+func (r CacheReport) projectNames() []string {
+	names := make([]string, 0, len(r.Projects))
+	for name := range r.Projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// taskHash returns the hash moon generated for an action's inputs.
+func taskHash(action Action) string {
+	for _, operation := range action.Operations {
+		if operation.Meta.Type == "hash-generation" && operation.Meta.Hash != "" {
+			return operation.Meta.Hash
+		}
+	}
+	return ""
+}
+
+func analyzeCache(report, baseline *RunReport) CacheReport {
+	result := CacheReport{Projects: map[string]*CacheStats{}, HasBaseline: baseline != nil}
+
+	baselineHashes := map[string]string{}
+	if baseline != nil {
+		for _, action := range baseline.Actions {
+			if action.Node.Action == "run-task" {
+				baselineHashes[action.Node.Params.Target] = taskHash(action)
+			}
+		}
+	}
+
+	for _, action := range report.Actions {
+		if action.Node.Action != "run-task" {
+			continue
+		}
+
+		project := parseTarget(action.Node.Params.Target).Project
+		stats, ok := result.Projects[project]
+		if !ok {
+			stats = &CacheStats{}
+			result.Projects[project] = stats
+		}
+		stats.add(action.Status)
+		result.Overall.add(action.Status)
+
+		ran := action.Status == "passed" || isFailedStatus(action.Status)
+		hash := taskHash(action)
+		if ran && hash != "" && baselineHashes[action.Node.Params.Target] == hash {
+			result.UncachedUnchanged = append(result.UncachedUnchanged, action.Node.Params.Target)
+		}
+	}
+
+	return result
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("%.1f%% hit rate (%d local, %d remote, %d executed)", s.HitRate(), s.Hits, s.RemoteHits, s.Misses)
+}
+
+func printCacheReport(renderer Renderer, cache CacheReport) {
+	renderer.Line(renderer.Bold(fmt.Sprintf("Cache: %s", cache.Overall)))
+	for _, project := range cache.projectNames() {
+		renderer.Line(fmt.Sprintf("  %s: %s", project, cache.Projects[project]))
+	}
+
+	if !cache.HasBaseline {
+		return
+	}
+	if len(cache.UncachedUnchanged) == 0 {
+		renderer.Line("No task ran uncached with unchanged inputs.")
+		return
+	}
+	renderer.Line(fmt.Sprintf("Ran uncached with unchanged inputs: %s", strings.Join(cache.UncachedUnchanged, ", ")))
+}
+
+// writeCacheOutputs writes step outputs in the name=value format GitHub
+// Actions reads from $GITHUB_OUTPUT.
+func writeCacheOutputs(out io.Writer, cache CacheReport) {
+	fmt.Fprintf(out, "cache-hit-rate=%.1f\n", cache.Overall.HitRate())
+	fmt.Fprintf(out, "cache-hits=%d\n", cache.Overall.Hits+cache.Overall.RemoteHits)
+	fmt.Fprintf(out, "cache-remote-hits=%d\n", cache.Overall.RemoteHits)
+	fmt.Fprintf(out, "cache-misses=%d\n", cache.Overall.Misses)
+	fmt.Fprintf(out, "uncached-unchanged=%s\n", strings.Join(cache.UncachedUnchanged, ","))
+}
+
+func appendGitHubOutput(path string, write func(io.Writer)) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open step outputs: %w", err)
+	}
+	write(file)
+	return file.Close()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func cacheAction(target, status, hash string) Action {
+	return Action{
+		Node:       ActionNode{Action: "run-task", Params: ActionParams{Target: target}},
+		Operations: []Operation{{Meta: OperationMeta{Type: "hash-generation", Hash: hash}}},
+		Status:     status,
+	}
+}
+
+func TestAnalyzeCache(t *testing.T) {
+	report := &RunReport{Actions: []Action{
+		cacheAction("toml:lint", "cached", "a1"),
+		cacheAction("toml:test", "passed", "b2"),
+		cacheAction("toml:fmt", "cached-from-remote", "c3"),
+		cacheAction("workspace:lint", "failed", "d4"),
+		cacheAction("workspace:validate", "skipped", "e5"),
+	}}
+	baseline := &RunReport{Actions: []Action{
+		cacheAction("toml:test", "passed", "b2"),
+		cacheAction("workspace:lint", "passed", "changed"),
+	}}
+
+	cache := analyzeCache(report, baseline)
+
+	if want := (CacheStats{Hits: 1, RemoteHits: 1, Misses: 2}); cache.Overall != want {
+		t.Errorf("Overall = %+v, want %+v", cache.Overall, want)
+	}
+	if got := cache.Projects["toml"].HitRate(); int(got*10) != 666 {
+		t.Errorf("toml HitRate() = %.2f, want 66.67", got)
+	}
+	if got := cache.Projects["workspace"].HitRate(); got != 0 {
+		t.Errorf("workspace HitRate() = %.2f, want 0", got)
+	}
+	if len(cache.UncachedUnchanged) != 1 || cache.UncachedUnchanged[0] != "toml:test" {
+		t.Errorf("UncachedUnchanged = %v, want [toml:test]", cache.UncachedUnchanged)
+	}
+
+	var outputs bytes.Buffer
+	writeCacheOutputs(&outputs, cache)
+	want := "cache-hit-rate=50.0\ncache-hits=2\ncache-remote-hits=1\ncache-misses=2\nuncached-unchanged=toml:test\n"
+	if outputs.String() != want {
+		t.Errorf("writeCacheOutputs() = %q, want %q", outputs.String(), want)
+	}
+}
+
+func TestPrintCacheReport(t *testing.T) {
+	report, err := loadReport(newDialect(DialectPlain, DialectOutput{}), fixture("statuses"))
+	if err != nil {
+		t.Fatalf("loadReport() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	renderer := newRenderer(FormatPlain, &out, newDialect(DialectPlain, DialectOutput{Commands: &out, Report: &out}))
+	printCacheReport(renderer, analyzeCache(report, report))
+	assertGolden(t, "statuses.cache", out.Bytes())
+}
+
+func TestRunUsesPreviousReportForCache(t *testing.T) {
+	dir := t.TempDir()
+	config, err := parseConfig([]string{
+		"-workspace", fixture("statuses"),
+		"-output", filepath.Join(dir, "report.txt"),
+		"-dialect", string(DialectPlain),
+		"-format", string(FormatPlain),
+		"-fail-on", string(FailNever),
+		"-previous-report", fixture("statuses"),
+		"-github-output", filepath.Join(dir, "outputs"),
+	}, func(string) string { return "" }, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(config, io.Discard); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	outputs, err := os.ReadFile(filepath.Join(dir, "outputs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	report, err := readReportPath(fixture("statuses"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCacheOutputs(&want, analyzeCache(report, report))
+	if !bytes.Equal(outputs, want.Bytes()) {
+		t.Errorf("step outputs = %q, want %q from comparing the report with itself", outputs, want.String())
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const commentExcerptLines = 15
+
+// commentMarker identifies the comment one platform's job owns, so reruns
+// update it instead of adding another.
+func commentMarker(label string) string {
+	return fmt.Sprintf("<!-- moon-report:%s -->", label)
+}
+
+// defaultCommentLabel names the platform the way the runner does, falling
+// back to the Go platform outside GitHub Actions.
+func defaultCommentLabel(getenv func(string) string) string {
+	if runnerOS, runnerArch := getenv("RUNNER_OS"), getenv("RUNNER_ARCH"); runnerOS != "" && runnerArch != "" {
+		return strings.ToLower(runnerOS + "-" + runnerArch)
+	}
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// writeCommentBody renders a compact markdown comment: status counts, the
+// failed targets with the end of their output, and the platform it ran on.
+func writeCommentBody(out io.Writer, label string, report *RunReport, config Config, masker *Masker) {
+	summary := summarize(report)
+
+	icon := "✅"
+	if summary.Outcome() == OutcomeFailed {
+		icon = "❌"
+	}
+
+	fmt.Fprintln(out, commentMarker(label))
+	fmt.Fprintf(out, "### %s moon run on `%s`\n\n", icon, label)
+	fmt.Fprintf(out, "%s\n", summary)
+
+	excerptOptions := LogOptions{TailLines: commentExcerptLines}
+	for _, action := range report.Actions {
+		if action.Node.Action != "run-task" || !isFailedStatus(action.Status) {
+			continue
+		}
+
+		identity := parseTarget(action.Node.Params.Target)
+		fmt.Fprintf(out, "\n<details>\n<summary>%s <code>%s</code></summary>\n\n", markdownBadge(action.Status), action.Node.Params.Target)
+
+		excerpt := failureExcerpt(config.WorkspaceRoot, identity, excerptOptions, masker)
+		if strings.TrimSpace(excerpt) != "" {
+			fence := markdownFence(excerpt)
+			fmt.Fprintf(out, "%stext\n%s\n%s\n\n", fence, strings.TrimRight(excerpt, "\n"), fence)
+		}
+		fmt.Fprintf(out, "</details>\n")
+	}
+}
+
+// failureExcerpt prefers stderr, then the output of failing Go tests, then
+// stdout.
+func failureExcerpt(root string, identity TargetIdentity, options LogOptions, masker *Masker) string {
+	output, err := readStatus(root, identity, options, masker)
+	if err != nil {
+		return ""
+	}
+	if strings.TrimSpace(output.Stderr) != "" {
+		return output.Stderr
+	}
+	if output.GoTests != nil {
+		var failing strings.Builder
+		for _, test := range output.GoTests.Tests {
+			if isFailedStatus(test.Status) && !test.HasSubtests {
+				failing.WriteString(test.Output())
+			}
+		}
+		if failing.Len() > 0 {
+			return truncateText(failing.String(), options)
+		}
+		return output.GoTests.Output
+	}
+	return output.Stdout
+}
+
+func writeCommentFile(path, label string, report *RunReport, config Config, masker *Masker) error {
+	var body bytes.Buffer
+	writeCommentBody(&body, label, report, config, masker)
+	if err := os.WriteFile(filepath.Clean(path), body.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write comment body: %w", err)
+	}
+	return nil
+}
+
+// CommentClient creates or updates an issue comment through the GitHub REST
+// API, finding an existing comment by its hidden marker.
+type CommentClient struct {
+	BaseURL    string
+	Token      string
+	Repository string
+	HTTPClient *http.Client
+}
+
+type issueComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+const commentsPerPage = 100
+
+// Upsert updates the first comment on the pull request containing marker, or
+// creates a new comment when there is none. It returns the comment id.
+func (c *CommentClient) Upsert(pullRequest int, marker, body string) (int64, error) {
+	existing, err := c.findComment(pullRequest, marker)
+	if err != nil {
+		return 0, err
+	}
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return 0, err
+	}
+
+	var comment issueComment
+	if existing != nil {
+		err = c.do(http.MethodPatch, fmt.Sprintf("/repos/%s/issues/comments/%d", c.Repository, existing.ID), payload, &comment)
+	} else {
+		err = c.do(http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", c.Repository, pullRequest), payload, &comment)
+	}
+	return comment.ID, err
+}
+
+func (c *CommentClient) findComment(pullRequest int, marker string) (*issueComment, error) {
+	for page := 1; ; page++ {
+		var comments []issueComment
+		path := fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=%d&page=%d", c.Repository, pullRequest, commentsPerPage, page)
+		if err := c.do(http.MethodGet, path, nil, &comments); err != nil {
+			return nil, err
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, marker) {
+				return &comment, nil
+			}
+		}
+		if len(comments) < commentsPerPage {
+			return nil, nil
+		}
+	}
+}
+
+func (c *CommentClient) do(method, path string, payload []byte, result any) error {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequest(method, strings.TrimRight(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	request.Header.Set("Authorization", "Bearer "+c.Token)
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, response.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s %s: failed to decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// runComment posts a body written by -comment-file. It is a separate
+// subcommand so generating the body never needs network access or a token.
+func runComment(args []string) {
+	flags := flag.NewFlagSet("comment", flag.ExitOnError)
+	file := flags.String("file", "", "comment body written by -comment-file")
+	pullRequest := flags.Int("pr", 0, "pull request number")
+	label := flags.String("label", defaultCommentLabel(os.Getenv), "label whose marker identifies the comment to update")
+	repository := flags.String("repo", os.Getenv("GITHUB_REPOSITORY"), "owner/name of the repository")
+	baseURL := flags.String("api-url", envOr("GITHUB_API_URL", "https://api.github.com"), "GitHub REST API base URL")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse comment flags: %v", err)
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	switch {
+	case *file == "":
+		log.Fatalf("comment needs -file pointing at the body written by -comment-file")
+	case *pullRequest <= 0:
+		log.Fatalf("comment needs -pr with the pull request number")
+	case *repository == "":
+		log.Fatalf("comment needs -repo or GITHUB_REPOSITORY")
+	case token == "":
+		log.Fatalf("comment needs GITHUB_TOKEN with pull-requests: write")
+	}
+
+	body, err := os.ReadFile(filepath.Clean(*file))
+	if err != nil {
+		log.Fatalf("Failed to read comment body: %v", err)
+	}
+
+	client := &CommentClient{BaseURL: *baseURL, Token: token, Repository: *repository}
+	id, err := client.Upsert(*pullRequest, commentMarker(*label), string(body))
+	if err != nil {
+		log.Fatalf("Failed to upsert comment: %v", err)
+	}
+	fmt.Printf("Comment %d is up to date\n", id)
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteCommentBody(t *testing.T) {
+	report, err := loadReport(newDialect(DialectPlain, DialectOutput{}), fixture("gotest"))
+	if err != nil {
+		t.Fatalf("loadReport() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	writeCommentBody(&out, "linux-x64", report, Config{WorkspaceRoot: fixture("gotest")}, nil)
+
+	if !strings.HasPrefix(out.String(), commentMarker("linux-x64")+"\n") {
+		t.Errorf("comment body does not start with its marker:\n%s", out.String())
+	}
+	assertGolden(t, "gotest.comment", out.Bytes())
+}
+
+// fakeIssues is a stand-in for the GitHub issue comments REST API.
+type fakeIssues struct {
+	mu       sync.Mutex
+	comments []issueComment
+	nextID   int64
+	requests []string
+}
+
+func (f *fakeIssues) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer test-token" || r.Header.Get("Accept") != "application/vnd.github+json" {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Body string `json:"body"`
+	}
+	if r.Body != nil && r.Method != http.MethodGet {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/ageha734/proto-plugins/issues/7/comments":
+		page := r.URL.Query().Get("page")
+		start := 0
+		if page == "2" {
+			start = commentsPerPage
+		}
+		end := min(start+commentsPerPage, len(f.comments))
+		writeJSON(w, http.StatusOK, f.comments[min(start, end):end])
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/ageha734/proto-plugins/issues/7/comments":
+		f.nextID++
+		comment := issueComment{ID: f.nextID, Body: payload.Body}
+		f.comments = append(f.comments, comment)
+		writeJSON(w, http.StatusCreated, comment)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/repos/ageha734/proto-plugins/issues/comments/"):
+		for i := range f.comments {
+			if fmt.Sprintf("/repos/ageha734/proto-plugins/issues/comments/%d", f.comments[i].ID) == r.URL.Path {
+				f.comments[i].Body = payload.Body
+				writeJSON(w, http.StatusOK, f.comments[i])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func TestCommentClientUpsert(t *testing.T) {
+	fake := &fakeIssues{}
+	for i := 0; i < commentsPerPage+3; i++ {
+		fake.nextID++
+		fake.comments = append(fake.comments, issueComment{ID: fake.nextID, Body: "looks good"})
+	}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := &CommentClient{BaseURL: server.URL, Token: "test-token", Repository: "ageha734/proto-plugins", HTTPClient: server.Client()}
+	marker := commentMarker("linux-x64")
+
+	created, err := client.Upsert(7, marker, marker+"\nfirst run")
+	if err != nil {
+		t.Fatalf("Upsert() create error = %v", err)
+	}
+
+	updated, err := client.Upsert(7, marker, marker+"\nsecond run")
+	if err != nil {
+		t.Fatalf("Upsert() update error = %v", err)
+	}
+
+	if created != updated {
+		t.Errorf("Upsert() updated comment %d, want the created comment %d", updated, created)
+	}
+	if last := fake.comments[len(fake.comments)-1]; last.Body != marker+"\nsecond run" {
+		t.Errorf("comment body = %q, want the second run", last.Body)
+	}
+
+	other, err := client.Upsert(7, commentMarker("macos-arm64"), commentMarker("macos-arm64")+"\nmacos")
+	if err != nil {
+		t.Fatalf("Upsert() other platform error = %v", err)
+	}
+	if other == created {
+		t.Error("a different marker should create its own comment")
+	}
+
+	client.Token = "wrong"
+	if _, err := client.Upsert(7, marker, "denied"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Upsert() with a bad token error = %v, want a 401", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Duration is the serde form moon uses for durations in reports.
+type Duration struct {
+	Secs  uint64 `json:"secs"`
+	Nanos uint32 `json:"nanos"`
+}
+
+func (d *Duration) Std() time.Duration {
+	if d == nil {
+		return 0
+	}
+	return time.Duration(d.Secs)*time.Second + time.Duration(d.Nanos)
+}
+
+// RegressionThreshold decides when a task got slower: it must exceed the
+// previous duration by Percent and by at least Minimum, so short tasks with
+// noisy timings are not flagged.
+type RegressionThreshold struct {
+	Percent int
+	Minimum time.Duration
+}
+
+func (t RegressionThreshold) regressed(before, after time.Duration) bool {
+	if before <= 0 {
+		return false
+	}
+	slower := after - before
+	return slower >= t.Minimum && slower*100 > before*time.Duration(t.Percent)
+}
+
+type DurationChange struct {
+	Target string
+	Before time.Duration
+	After  time.Duration
+}
+
+// Comparison lists what changed between a previous run and the current one.
+type Comparison struct {
+	NewlyFailing []string
+	NewlyFixed   []string
+	Regressions  []DurationChange
+}
+
+func (c Comparison) empty() bool {
+	return len(c.NewlyFailing) == 0 && len(c.NewlyFixed) == 0 && len(c.Regressions) == 0
+}
+
+// compareReports matches run-task actions by target. Tasks missing from the
+// previous run count as newly failing when they fail; durations are only
+// compared when both runs executed the task, since cache restores say
+// nothing about how long the task takes.
+func compareReports(previous, current *RunReport, threshold RegressionThreshold) Comparison {
+	before := map[string]Action{}
+	for _, action := range previous.Actions {
+		if action.Node.Action == "run-task" {
+			before[action.Node.Params.Target] = action
+		}
+	}
+
+	var comparison Comparison
+	for _, action := range current.Actions {
+		if action.Node.Action != "run-task" {
+			continue
+		}
+
+		target := action.Node.Params.Target
+		old, existed := before[target]
+		failed, failedBefore := isFailedStatus(action.Status), existed && isFailedStatus(old.Status)
+
+		switch {
+		case failed && !failedBefore:
+			comparison.NewlyFailing = append(comparison.NewlyFailing, target)
+		case !failed && failedBefore && action.Status != "skipped":
+			comparison.NewlyFixed = append(comparison.NewlyFixed, target)
+		}
+
+		if existed && executed(action.Status) && executed(old.Status) {
+			change := DurationChange{Target: target, Before: old.Duration.Std(), After: action.Duration.Std()}
+			if threshold.regressed(change.Before, change.After) {
+				comparison.Regressions = append(comparison.Regressions, change)
+			}
+		}
+	}
+	return comparison
+}
+
+func executed(status string) bool {
+	return status == "passed" || isFailedStatus(status)
+}
+
+func (c DurationChange) String() string {
+	return fmt.Sprintf("%s (%s → %s)", c.Target, c.Before.Round(time.Millisecond), c.After.Round(time.Millisecond))
+}
+
+func printComparison(renderer Renderer, comparison Comparison) {
+	renderer.Line(renderer.Bold("Compared with the previous run:"))
+	if comparison.empty() {
+		renderer.Line("  No new failures, fixes or slowdowns.")
+		return
+	}
+
+	for _, target := range comparison.NewlyFailing {
+		renderer.Line(fmt.Sprintf("  newly failing: %s", target))
+	}
+	for _, target := range comparison.NewlyFixed {
+		renderer.Line(fmt.Sprintf("  newly fixed: %s", target))
+	}
+	for _, change := range comparison.Regressions {
+		renderer.Line(fmt.Sprintf("  slower: %s", change))
+	}
+}
+
+// writeComparisonSummary appends the comparison to the job summary.
+func writeComparisonSummary(out io.Writer, comparison Comparison) {
+	if comparison.empty() {
+		return
+	}
+
+	fmt.Fprintf(out, "### Compared with the previous run\n\n")
+	fmt.Fprintf(out, "| Change | Target |\n")
+	fmt.Fprintf(out, "| --- | --- |\n")
+	for _, target := range comparison.NewlyFailing {
+		fmt.Fprintf(out, "| 🔴 newly failing | `%s` |\n", target)
+	}
+	for _, target := range comparison.NewlyFixed {
+		fmt.Fprintf(out, "| 🟢 newly fixed | `%s` |\n", target)
+	}
+	for _, change := range comparison.Regressions {
+		fmt.Fprintf(out, "| 🐢 slower, %s → %s | `%s` |\n", change.Before.Round(time.Millisecond), change.After.Round(time.Millisecond), change.Target)
+	}
+	fmt.Fprintln(out)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func timedAction(target, status string, seconds uint64) Action {
+	return Action{
+		Node:     ActionNode{Action: "run-task", Params: ActionParams{Target: target}},
+		Status:   status,
+		Duration: &Duration{Secs: seconds},
+	}
+}
+
+func TestCompareReports(t *testing.T) {
+	previous := &RunReport{Actions: []Action{
+		timedAction("toml:test", "passed", 60),
+		timedAction("toml:lint", "failed", 5),
+		timedAction("toml:fmt", "passed", 4),
+		timedAction("workspace:lint", "passed", 20),
+		timedAction("workspace:validate", "failed", 1),
+	}}
+	current := &RunReport{Actions: []Action{
+		timedAction("toml:test", "passed", 100),
+		timedAction("toml:lint", "cached", 0),
+		timedAction("toml:fmt", "passed", 9),
+		timedAction("workspace:lint", "timed-out", 25),
+		timedAction("workspace:validate", "skipped", 0),
+		timedAction("workspace:new", "failed", 3),
+	}}
+
+	got := compareReports(previous, current, RegressionThreshold{Percent: 50, Minimum: 10 * time.Second})
+	want := Comparison{
+		NewlyFailing: []string{"workspace:lint", "workspace:new"},
+		NewlyFixed:   []string{"toml:lint"},
+		Regressions:  []DurationChange{{Target: "toml:test", Before: time.Minute, After: 100 * time.Second}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareReports() = %+v, want %+v", got, want)
+	}
+
+	var summary bytes.Buffer
+	writeComparisonSummary(&summary, got)
+	if !bytes.Contains(summary.Bytes(), []byte("| 🐢 slower, 1m0s → 1m40s | `toml:test` |")) {
+		t.Errorf("writeComparisonSummary() = %s", summary.String())
+	}
+}
+
+func TestActionDuration(t *testing.T) {
+	var action Action
+	if err := json.Unmarshal([]byte(`{"status":"passed","duration":{"secs":2,"nanos":500000000}}`), &action); err != nil {
+		t.Fatal(err)
+	}
+	if got := action.Duration.Std(); got != 2500*time.Millisecond {
+		t.Errorf("Duration.Std() = %s, want 2.5s", got)
+	}
+}
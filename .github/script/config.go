@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix is prepended to every flag name, upper-cased with dashes turned
+// into underscores, to form the environment variable that sets its default,
+// unless envNames names another.
+const envPrefix = "MOON_REPORT_"
+
+// Config holds the inputs of a report run. Every field can be set by a flag
+// or by the matching MOON_REPORT_* environment variable; flags win.
+type Config struct {
+	WorkspaceRoot  string
+	ReportPath     string
+	FailOn         FailPolicy
+	Format         Format
+	Dialect        DialectName
+	Logs           LogOptions
+	MaskEnv        []string
+	Expand         map[string]bool
+	IncludePassing bool
+	Output         string
+	StepSummary    string
+	CommentFile    string
+	CommentLabel   string
+	GitHubOutput   string
+	PreviousReport string
+	Regression     RegressionThreshold
+	SARIF          string
+}
+
+// expands reports whether a task's output should be printed. An empty
+// Expand set expands every status.
+func (c Config) expands(status string) bool {
+	return len(c.Expand) == 0 || c.Expand[status]
+}
+
+// shows reports whether a task appears in the output at all.
+func (c Config) shows(status string) bool {
+	return c.IncludePassing || isFailedStatus(status)
+}
+
+// envNames overrides the environment variable of flags whose derived name
+// would repeat the prefix.
+var envNames = map[string]string{"report": "MOON_REPORT_PATH"}
+
+func envName(flagName string) string {
+	if name, ok := envNames[flagName]; ok {
+		return name
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// parseConfig reads flags from args with defaults taken from getenv, then
+// validates the result. All problems are reported together.
+func parseConfig(args []string, getenv func(string) string, output io.Writer) (Config, error) {
+	var problems []error
+
+	envString := func(name, fallback string) string {
+		if value := getenv(envName(name)); value != "" {
+			return value
+		}
+		return fallback
+	}
+	envInt := func(name string, fallback int) int {
+		value := getenv(envName(name))
+		if value == "" {
+			return fallback
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s=%q is not a whole number", envName(name), value))
+			return fallback
+		}
+		return number
+	}
+	envBool := func(name string, fallback bool) bool {
+		value := getenv(envName(name))
+		if value == "" {
+			return fallback
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s=%q is not true or false", envName(name), value))
+			return fallback
+		}
+		return parsed
+	}
+
+	envDuration := func(name string, fallback time.Duration) time.Duration {
+		value := getenv(envName(name))
+		if value == "" {
+			return fallback
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s=%q is not a duration such as 30s", envName(name), value))
+			return fallback
+		}
+		return parsed
+	}
+
+	flags := flag.NewFlagSet("action", flag.ContinueOnError)
+	flags.SetOutput(output)
+
+	var config Config
+	flags.StringVar(&config.WorkspaceRoot, "workspace", envString("workspace", ""), "moon workspace root (defaults to the current directory)")
+	flags.StringVar(&config.ReportPath, "report", envString("report", ""), "explicit ciReport.json or runReport.json path (defaults to searching .moon/cache)")
+	failOn := flags.String("fail-on", envString("fail-on", string(FailOnFailure)), "when to exit non-zero: never, failure or failure-or-missing")
+	format := flags.String("format", envString("format", string(FormatAuto)), "output format: auto, ansi, plain or markdown")
+	dialect := flags.String("dialect", envString("dialect", string(DialectAuto)), "CI log dialect: auto, github, gitlab or plain")
+	flags.IntVar(&config.Logs.HeadLines, "head-lines", envInt("head-lines", 100), "lines to print from the start of each task log (0 with -tail-lines=0 prints everything)")
+	flags.IntVar(&config.Logs.TailLines, "tail-lines", envInt("tail-lines", 300), "lines to print from the end of each task log")
+	flags.StringVar(&config.Logs.ArtifactDir, "log-dir", envString("log-dir", ""), "directory to write full task logs to")
+	maskEnv := flags.String("mask-env", envString("mask-env", strings.Join(defaultMaskEnv, ",")), "comma-separated environment variable names (globs allowed) whose values are masked")
+	expand := flags.String("expand", envString("expand", ""), "comma-separated statuses whose output is printed (defaults to all)")
+	flags.BoolVar(&config.IncludePassing, "include-passing", envBool("include-passing", true), "list tasks that did not fail")
+	flags.StringVar(&config.Output, "output", envString("output", "-"), "file to write the report to, or - for stdout")
+	flags.StringVar(&config.CommentFile, "comment-file", envString("comment-file", ""), "file to write a pull request comment body to")
+	flags.StringVar(&config.CommentLabel, "comment-label", envString("comment-label", defaultCommentLabel(getenv)), "platform label shown in the comment and used in its update marker")
+	flags.StringVar(&config.SARIF, "sarif", envString("sarif", ""), "file to write golangci-lint and dprint findings to as SARIF 2.1.0")
+	flags.StringVar(&config.PreviousReport, "previous-report", envString("previous-report", ""), "report file or directory from a previous run to compare failures, durations and task hashes against")
+	flags.IntVar(&config.Regression.Percent, "regression-percent", envInt("regression-percent", 50), "percentage a task must slow down by to be reported as a regression")
+	flags.DurationVar(&config.Regression.Minimum, "regression-min", envDuration("regression-min", 10*time.Second), "smallest slowdown reported as a regression")
+	flags.StringVar(&config.GitHubOutput, "github-output", envString("github-output", getenv("GITHUB_OUTPUT")), "file to append step outputs to (defaults to $GITHUB_OUTPUT)")
+	flags.StringVar(&config.StepSummary, "step-summary", envString("step-summary", getenv("GITHUB_STEP_SUMMARY")), "markdown file to append the job summary to (defaults to $GITHUB_STEP_SUMMARY)")
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if flags.NArg() > 0 {
+		problems = append(problems, fmt.Errorf("unexpected arguments %q (did you mean the merge or comment subcommand?)", flags.Args()))
+	}
+
+	var err error
+	if config.FailOn, err = parseFailPolicy(*failOn); err != nil {
+		problems = append(problems, fmt.Errorf("-fail-on/%s: %w", envName("fail-on"), err))
+	}
+	if config.Format, err = parseFormat(*format); err != nil {
+		problems = append(problems, fmt.Errorf("-format/%s: %w", envName("format"), err))
+	}
+	if config.Dialect, err = parseDialect(*dialect); err != nil {
+		problems = append(problems, fmt.Errorf("-dialect/%s: %w", envName("dialect"), err))
+	}
+	if config.Expand, err = parseStatuses(*expand); err != nil {
+		problems = append(problems, fmt.Errorf("-expand/%s: %w", envName("expand"), err))
+	}
+	config.MaskEnv = splitList(*maskEnv)
+
+	problems = append(problems, config.validate()...)
+	if len(problems) > 0 {
+		return Config{}, errors.Join(problems...)
+	}
+	return config, nil
+}
+
+func parseStatuses(value string) (map[string]bool, error) {
+	statuses := map[string]bool{}
+	for _, status := range splitList(value) {
+		if _, ok := statusBadges[status]; !ok {
+			return nil, fmt.Errorf("unknown status %q (expected one of %s)", status, strings.Join(knownStatuses(), ", "))
+		}
+		statuses[status] = true
+	}
+	return statuses, nil
+}
+
+func knownStatuses() []string {
+	statuses := make([]string, 0, len(statusBadges))
+	for status := range statusBadges {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+func (c *Config) validate() []error {
+	var problems []error
+
+	if c.Logs.HeadLines < 0 || c.Logs.TailLines < 0 {
+		problems = append(problems, fmt.Errorf("-head-lines/-tail-lines: line counts must not be negative"))
+	}
+
+	if c.WorkspaceRoot == "" {
+		root, err := os.Getwd()
+		if err != nil {
+			problems = append(problems, fmt.Errorf("-workspace/%s is not set and the current directory is unavailable: %w", envName("workspace"), err))
+		}
+		c.WorkspaceRoot = root
+	} else if info, err := os.Stat(c.WorkspaceRoot); err != nil || !info.IsDir() {
+		problems = append(problems, fmt.Errorf("-workspace/%s: %s is not a directory; point it at the moon workspace root", envName("workspace"), c.WorkspaceRoot))
+	}
+
+	if c.ReportPath != "" {
+		if info, err := os.Stat(c.ReportPath); err != nil || info.IsDir() {
+			problems = append(problems, fmt.Errorf("-report/%s: %s is not a file; pass the ciReport.json or runReport.json written by moon", envName("report"), c.ReportPath))
+		}
+	}
+
+	if c.PreviousReport != "" {
+		if _, err := os.Stat(c.PreviousReport); err != nil {
+			problems = append(problems, fmt.Errorf("-previous-report/%s: %s does not exist; pass a report file or the directory it was downloaded to", envName("previous-report"), c.PreviousReport))
+		}
+	}
+
+	if c.Regression.Percent < 0 || c.Regression.Minimum < 0 {
+		problems = append(problems, fmt.Errorf("-regression-percent/-regression-min: thresholds must not be negative"))
+	}
+
+	if c.Output == "" {
+		problems = append(problems, fmt.Errorf("-output/%s is empty; use - for stdout or a file path", envName("output")))
+	}
+
+	return problems
+}
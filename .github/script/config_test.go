@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		check   func(t *testing.T, config Config)
+		wantErr []string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, config Config) {
+				if config.FailOn != FailOnFailure || config.Format != FormatAuto || !config.IncludePassing || config.Output != "-" {
+					t.Errorf("defaults = %+v", config)
+				}
+				if !config.expands("passed") || !config.expands("failed") {
+					t.Error("empty -expand should expand every status")
+				}
+			},
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				"MOON_REPORT_WORKSPACE":       fixture("statuses"),
+				"MOON_REPORT_EXPAND":          "failed, timed-out",
+				"MOON_REPORT_INCLUDE_PASSING": "false",
+				"MOON_REPORT_HEAD_LINES":      "5",
+				"MOON_REPORT_PATH":            fixture("statuses/.moon/cache/ciReport.json"),
+				"GITHUB_STEP_SUMMARY":         "/tmp/summary.md",
+			},
+			check: func(t *testing.T, config Config) {
+				if config.WorkspaceRoot != fixture("statuses") || config.ReportPath != fixture("statuses/.moon/cache/ciReport.json") || config.Logs.HeadLines != 5 || config.StepSummary != "/tmp/summary.md" {
+					t.Errorf("config = %+v", config)
+				}
+				if config.expands("passed") || !config.expands("timed-out") {
+					t.Errorf("Expand = %v, want failed and timed-out", config.Expand)
+				}
+				if config.shows("cached") || !config.shows("failed") {
+					t.Error("include-passing=false should hide passing tasks only")
+				}
+			},
+		},
+		{
+			name: "flags override environment",
+			args: []string{"-format", "markdown", "-head-lines", "7"},
+			env:  map[string]string{"MOON_REPORT_FORMAT": "plain", "MOON_REPORT_HEAD_LINES": "5"},
+			check: func(t *testing.T, config Config) {
+				if config.Format != FormatMarkdown || config.Logs.HeadLines != 7 {
+					t.Errorf("config = %+v, want flags to win", config)
+				}
+			},
+		},
+		{
+			name: "every problem is reported",
+			args: []string{"-fail-on", "sometimes", "-expand", "exploded", "-report", "testdata/missing.json", "-workspace", "testdata/nowhere"},
+			env:  map[string]string{"MOON_REPORT_TAIL_LINES": "many"},
+			wantErr: []string{
+				"MOON_REPORT_TAIL_LINES=\"many\" is not a whole number",
+				"-fail-on/MOON_REPORT_FAIL_ON: unknown fail policy \"sometimes\"",
+				"-expand/MOON_REPORT_EXPAND: unknown status \"exploded\"",
+				"-report/MOON_REPORT_PATH: testdata/missing.json is not a file",
+				"-workspace/MOON_REPORT_WORKSPACE: testdata/nowhere is not a directory",
+			},
+		},
+		{
+			name:    "stray arguments",
+			args:    []string{"report.json"},
+			wantErr: []string{"unexpected arguments"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(name string) string { return tt.env[name] }
+			config, err := parseConfig(tt.args, getenv, io.Discard)
+
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatal("parseConfig() succeeded, want error")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("parseConfig() error = %v\nwant it to mention %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type DialectName string
+
+const (
+	DialectAuto   DialectName = "auto"
+	DialectGitHub DialectName = "github"
+	DialectGitLab DialectName = "gitlab"
+	DialectPlain  DialectName = "plain"
+)
+
+// Dialect emits the log annotations a CI system understands: debug, warning
+// and error messages, secret masks, and collapsible groups around each task.
+type Dialect interface {
+	Mask(value string)
+	Debug(message string)
+	Warning(message string)
+	Error(message string)
+	StartGroup(title string)
+	EndGroup()
+}
+
+func parseDialect(value string) (DialectName, error) {
+	switch name := DialectName(strings.TrimSpace(value)); name {
+	case DialectAuto, DialectGitHub, DialectGitLab, DialectPlain:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown dialect %q (expected %s, %s, %s or %s)", value, DialectAuto, DialectGitHub, DialectGitLab, DialectPlain)
+	}
+}
+
+func detectDialect(name DialectName) DialectName {
+	if name != DialectAuto {
+		return name
+	}
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return DialectGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return DialectGitLab
+	default:
+		return DialectPlain
+	}
+}
+
+// DialectOutput says where a dialect writes. Commands receives annotations,
+// which the CI system reads from the job's stdout even when the report goes to
+// a file, and Report receives the groups framing each task in the report.
+// Both should redact secrets; Masks receives the secrets themselves, so it
+// must not, and like Commands it is the job's stdout. Color follows the
+// renderer's decision on ANSI escapes. A nil writer discards its output.
+type DialectOutput struct {
+	Commands io.Writer
+	Report   io.Writer
+	Masks    io.Writer
+	Color    bool
+}
+
+func newDialect(name DialectName, output DialectOutput) Dialect {
+	commands, report, masks := discardNil(output.Commands), discardNil(output.Report), discardNil(output.Masks)
+	switch name {
+	case DialectGitHub:
+		return &githubDialect{commands: commands, report: report, masks: masks}
+	case DialectGitLab:
+		return &gitlabDialect{commands: commands, report: report, color: output.Color, now: time.Now}
+	default:
+		return &plainDialect{commands: commands, report: report}
+	}
+}
+
+func discardNil(out io.Writer) io.Writer {
+	if out == nil {
+		return io.Discard
+	}
+	return out
+}
+
+type githubDialect struct {
+	commands io.Writer
+	report   io.Writer
+	masks    io.Writer
+}
+
+func (d *githubDialect) Debug(message string)    { fmt.Fprintf(d.commands, "::debug::%s\n", message) }
+func (d *githubDialect) Warning(message string)  { fmt.Fprintf(d.commands, "::warning::%s\n", message) }
+func (d *githubDialect) Error(message string)    { fmt.Fprintf(d.commands, "::error::%s\n", message) }
+func (d *githubDialect) StartGroup(title string) { fmt.Fprintf(d.report, "::group::%s\n", title) }
+func (d *githubDialect) EndGroup()               { fmt.Fprintln(d.report, "::endgroup::") }
+
+// Mask registers each line of value on its own: GitHub masks a value only up
+// to its first newline, which would leave the rest of a key file visible.
+func (d *githubDialect) Mask(value string) {
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			fmt.Fprintf(d.masks, "::add-mask::%s\n", line)
+		}
+	}
+}
+
+// gitlabDialect renders groups as GitLab CI collapsible sections. Section
+// names must be unique within a job, so each one gets a sequence number.
+type gitlabDialect struct {
+	commands io.Writer
+	report   io.Writer
+	color    bool
+	now      func() time.Time
+	sequence int
+	open     []string
+}
+
+var gitlabSectionName = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func (d *gitlabDialect) Mask(string) {}
+
+func (d *gitlabDialect) Debug(string) {}
+
+func (d *gitlabDialect) Warning(message string) { d.annotate("33", "WARNING: "+message) }
+
+func (d *gitlabDialect) Error(message string) { d.annotate("31", "ERROR: "+message) }
+
+// annotate colors a message the way GitLab's own job log does, unless colors
+// are turned off.
+func (d *gitlabDialect) annotate(color, message string) {
+	if !d.color {
+		fmt.Fprintln(d.commands, message)
+		return
+	}
+	fmt.Fprintf(d.commands, "\u001b[%sm%s\u001b[39m\n", color, message)
+}
+
+func (d *gitlabDialect) StartGroup(title string) {
+	d.sequence++
+	name := fmt.Sprintf("task_%d_%s", d.sequence, strings.Trim(gitlabSectionName.ReplaceAllString(stripANSI(title), "_"), "_"))
+	d.open = append(d.open, name)
+	fmt.Fprintf(d.report, "\u001b[0Ksection_start:%d:%s[collapsed=true]\r\u001b[0K%s\n", d.now().Unix(), name, title)
+}
+
+func (d *gitlabDialect) EndGroup() {
+	if len(d.open) == 0 {
+		return
+	}
+	name := d.open[len(d.open)-1]
+	d.open = d.open[:len(d.open)-1]
+	fmt.Fprintf(d.report, "\u001b[0Ksection_end:%d:%s\r\u001b[0K\n", d.now().Unix(), name)
+}
+
+type plainDialect struct {
+	commands io.Writer
+	report   io.Writer
+}
+
+func (d *plainDialect) Mask(string)             {}
+func (d *plainDialect) Debug(string)            {}
+func (d *plainDialect) Warning(message string)  { fmt.Fprintf(d.commands, "warning: %s\n", message) }
+func (d *plainDialect) Error(message string)    { fmt.Fprintf(d.commands, "error: %s\n", message) }
+func (d *plainDialect) StartGroup(title string) { fmt.Fprintf(d.report, "==> %s\n", title) }
+func (d *plainDialect) EndGroup()               { fmt.Fprintln(d.report) }
+
+var ansiEscape = regexp.MustCompile("\u001b\\[[0-9;]*[a-zA-Z]")
+
+func stripANSI(text string) string {
+	return ansiEscape.ReplaceAllString(text, "")
+}
@@ -1,3 +1,5 @@
 module github.com/ageha734/proto-plugins/action
 
 go 1.24.8
+
+require gopkg.in/yaml.v3 v3.0.1
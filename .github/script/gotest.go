@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GoTestEvent is one line of `go test -json` output.
+type GoTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// GoTest is a test or subtest with its own output. Tests that ran subtests
+// are containers whose results are reported through the subtests.
+type GoTest struct {
+	Package     string
+	Name        string
+	Status      string
+	Elapsed     time.Duration
+	HasSubtests bool
+	output      *truncatedLog
+}
+
+func (t *GoTest) Output() string {
+	return t.output.render("")
+}
+
+// GoTestRun holds the tests of a go test -json stream in the order they
+// started, plus package-level output such as build errors and ok/FAIL lines.
+type GoTestRun struct {
+	Tests  []*GoTest
+	Output string
+}
+
+// goTestStatuses maps test actions onto the moon statuses the renderers know.
+var goTestStatuses = map[string]string{
+	"pass": "passed",
+	"fail": "failed",
+	"skip": "skipped",
+}
+
+// isGoTestJSON reports whether the first non-empty line of path is a go test
+// -json event.
+func isGoTestJSON(path string) (bool, error) {
+	exists, err := fileExists(path)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close log file: %v", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		_, ok := parseGoTestEvent(line)
+		return ok, nil
+	}
+	return false, scanner.Err()
+}
+
+func parseGoTestEvent(line string) (GoTestEvent, bool) {
+	if !strings.HasPrefix(line, "{") {
+		return GoTestEvent{}, false
+	}
+	var event GoTestEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event.Action == "" {
+		return GoTestEvent{}, false
+	}
+	return event, true
+}
+
+// readGoTestLog streams a go test -json log into per-test groups. Each test's
+// output is truncated on its own, so one noisy test cannot hide the others.
+func readGoTestLog(path, artifact string, options LogOptions, masker *Masker) (*GoTestRun, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close log file: %v", err)
+		}
+	}()
+
+	sink := io.Discard
+	if artifact != "" {
+		copyFile, err := createArtifact(artifact)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := copyFile.Close(); err != nil {
+				log.Printf("Failed to close artifact file: %v", err)
+			}
+		}()
+		sink = copyFile
+	}
+
+	collector := newGoTestCollector(options)
+	err = eachLine(file, func(line string) error {
+		line = masker.Redact(line)
+		if _, err := io.WriteString(sink, line); err != nil {
+			return fmt.Errorf("failed to write artifact: %w", err)
+		}
+		collector.add(line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collector.run(), nil
+}
+
+type goTestCollector struct {
+	options LogOptions
+	tests   map[string]*GoTest
+	order   []*GoTest
+	output  *truncatedLog
+}
+
+func newGoTestCollector(options LogOptions) *goTestCollector {
+	return &goTestCollector{options: options, tests: map[string]*GoTest{}, output: newTruncatedLog(options)}
+}
+
+func (c *goTestCollector) add(line string) {
+	event, ok := parseGoTestEvent(strings.TrimSpace(line))
+	if !ok {
+		c.output.add(line)
+		return
+	}
+
+	if event.Test == "" {
+		if event.Output != "" {
+			c.output.add(event.Output)
+		}
+		return
+	}
+
+	test := c.test(event.Package, event.Test)
+	if parent := strings.LastIndex(event.Test, "/"); parent > 0 {
+		c.test(event.Package, event.Test[:parent]).HasSubtests = true
+	}
+
+	if event.Output != "" {
+		test.output.add(event.Output)
+	}
+	if status, ok := goTestStatuses[event.Action]; ok {
+		test.Status = status
+		test.Elapsed = time.Duration(event.Elapsed * float64(time.Second))
+	}
+}
+
+func (c *goTestCollector) test(pkg, name string) *GoTest {
+	key := pkg + "\x00" + name
+	test, ok := c.tests[key]
+	if !ok {
+		test = &GoTest{Package: pkg, Name: name, Status: "running", output: newTruncatedLog(c.options)}
+		c.tests[key] = test
+		c.order = append(c.order, test)
+	}
+	return test
+}
+
+// run finishes collection. Tests that never reported a result, usually
+// because the binary panicked or timed out, are marked aborted.
+func (c *goTestCollector) run() *GoTestRun {
+	for _, test := range c.order {
+		if test.Status == "running" {
+			test.Status = "aborted"
+		}
+	}
+	return &GoTestRun{Tests: c.order, Output: c.output.render("")}
+}
+
+// printGoTests renders one group per test after the task's own group,
+// leaving out containers since their subtests have groups of their own.
+// Tests are shown and expanded by their status, like tasks.
+func printGoTests(renderer Renderer, target string, run *GoTestRun, config Config) {
+	for _, test := range run.Tests {
+		if test.HasSubtests || !config.shows(test.Status) {
+			continue
+		}
+		title := fmt.Sprintf("%s %s › %s (%s)", renderer.Badge(test.Status), target, renderer.Bold(test.Name), test.Elapsed.Round(time.Millisecond))
+		if !config.expands(test.Status) {
+			renderer.Line(title)
+			continue
+		}
+		renderer.StartGroup(title)
+		if output := test.Output(); strings.TrimSpace(output) != "" {
+			renderer.Output(StreamStdout, output)
+		}
+		renderer.EndGroup()
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LogOptions controls how much of a task's output is printed. Zero head and
+// tail limits print the whole log.
+type LogOptions struct {
+	HeadLines   int
+	TailLines   int
+	ArtifactDir string
+}
+
+func (o LogOptions) truncates() bool {
+	return o.HeadLines > 0 || o.TailLines > 0
+}
+
+// TaskOutput is what a task printed. GoTests is set instead of Stdout when
+// stdout was a go test -json stream.
+type TaskOutput struct {
+	Stdout  string
+	Stderr  string
+	GoTests *GoTestRun
+}
+
+func readStatus(workspaceRoot string, identity TargetIdentity, options LogOptions, masker *Masker) (TaskOutput, error) {
+	statusDir := filepath.Join(workspaceRoot, ".moon", "cache", "states", identity.Project, identity.Task)
+	stdoutPath := filepath.Join(statusDir, "stdout.log")
+	var output TaskOutput
+
+	goTestJSON, err := isGoTestJSON(stdoutPath)
+	if err != nil {
+		return TaskOutput{}, fmt.Errorf("failed to read stdout log: %w", err)
+	}
+
+	if goTestJSON {
+		output.GoTests, err = readGoTestLog(stdoutPath, artifactPath(options, identity, "stdout.log"), options, masker)
+	} else {
+		output.Stdout, err = readLog(stdoutPath, artifactPath(options, identity, "stdout.log"), options, masker)
+	}
+	if err != nil {
+		return TaskOutput{}, fmt.Errorf("failed to read stdout log: %w", err)
+	}
+
+	output.Stderr, err = readLog(filepath.Join(statusDir, "stderr.log"), artifactPath(options, identity, "stderr.log"), options, masker)
+	if err != nil {
+		return TaskOutput{}, fmt.Errorf("failed to read stderr log: %w", err)
+	}
+
+	return output, nil
+}
+
+func artifactPath(options LogOptions, identity TargetIdentity, fileName string) string {
+	if options.ArtifactDir == "" {
+		return ""
+	}
+	return filepath.Join(options.ArtifactDir, identity.Project, identity.Task, fileName)
+}
+
+// readLog streams path line by line, keeping only the configured head and
+// tail, and copies the full log to artifact when one is given. Every line is
+// redacted before it is kept or copied.
+func readLog(path, artifact string, options LogOptions, masker *Masker) (string, error) {
+	exists, err := fileExists(path)
+	if err != nil || !exists {
+		return "", err
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close log file: %v", err)
+		}
+	}()
+
+	sink := io.Discard
+	if artifact != "" {
+		copyFile, err := createArtifact(artifact)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			if err := copyFile.Close(); err != nil {
+				log.Printf("Failed to close artifact file: %v", err)
+			}
+		}()
+		sink = copyFile
+	}
+
+	truncated, err := truncateLines(file, options, masker, sink)
+	if err != nil {
+		return "", err
+	}
+
+	if truncated.omitted > 0 && artifact != "" {
+		return truncated.render(fmt.Sprintf("full log: %s", artifact)), nil
+	}
+	return truncated.render(""), nil
+}
+
+func createArtifact(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact file: %w", err)
+	}
+	return file, nil
+}
+
+// truncatedLog keeps the first HeadLines lines and a ring buffer of the last
+// TailLines lines, so memory stays bounded regardless of the log size.
+type truncatedLog struct {
+	options LogOptions
+	head    []string
+	ring    []string
+	next    int
+	omitted int
+}
+
+func newTruncatedLog(options LogOptions) *truncatedLog {
+	return &truncatedLog{options: options, ring: make([]string, 0, options.TailLines)}
+}
+
+func (l *truncatedLog) add(line string) {
+	switch {
+	case !l.options.truncates() || len(l.head) < l.options.HeadLines:
+		l.head = append(l.head, line)
+	case l.options.TailLines == 0:
+		l.omitted++
+	case len(l.ring) < l.options.TailLines:
+		l.ring = append(l.ring, line)
+	default:
+		l.ring[l.next] = line
+		l.next = (l.next + 1) % l.options.TailLines
+		l.omitted++
+	}
+}
+
+func (l *truncatedLog) render(note string) string {
+	var builder strings.Builder
+	for _, line := range l.head {
+		builder.WriteString(line)
+	}
+	if l.omitted > 0 {
+		marker := fmt.Sprintf("... %d lines omitted ...", l.omitted)
+		if note != "" {
+			marker = fmt.Sprintf("... %d lines omitted (%s) ...", l.omitted, note)
+		}
+		builder.WriteString(marker + "\n")
+	}
+	for _, line := range l.ring[l.next:] {
+		builder.WriteString(line)
+	}
+	for _, line := range l.ring[:l.next] {
+		builder.WriteString(line)
+	}
+	return builder.String()
+}
+
+// truncateLines feeds every redacted line of reader into a truncatedLog and
+// also writes it to sink.
+func truncateLines(reader io.Reader, options LogOptions, masker *Masker, sink io.Writer) (*truncatedLog, error) {
+	result := newTruncatedLog(options)
+	err := eachLine(reader, func(line string) error {
+		line = masker.Redact(line)
+		if _, err := io.WriteString(sink, line); err != nil {
+			return fmt.Errorf("failed to write artifact: %w", err)
+		}
+		result.add(line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// eachLine calls fn for every line of reader, including the trailing newline.
+func eachLine(reader io.Reader, fn func(line string) error) error {
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadString('\n')
+		if line != "" {
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
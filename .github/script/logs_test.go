@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTruncateLines(t *testing.T) {
+	input := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+
+	tests := []struct {
+		name    string
+		options LogOptions
+		want    string
+	}{
+		{name: "unlimited", options: LogOptions{}, want: input},
+		{name: "fits", options: LogOptions{HeadLines: 5, TailLines: 5}, want: input},
+		{name: "head and tail", options: LogOptions{HeadLines: 2, TailLines: 3}, want: "1\n2\n... 5 lines omitted ...\n8\n9\n10\n"},
+		{name: "head only", options: LogOptions{HeadLines: 3}, want: "1\n2\n3\n... 7 lines omitted ...\n"},
+		{name: "tail only", options: LogOptions{TailLines: 2}, want: "... 8 lines omitted ...\n9\n10\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sink bytes.Buffer
+			truncated, err := truncateLines(strings.NewReader(input), tt.options, nil, &sink)
+			if err != nil {
+				t.Fatalf("truncateLines() error = %v", err)
+			}
+			if got := truncated.render(""); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+			if sink.String() != input {
+				t.Errorf("sink = %q, want the full log", sink.String())
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -17,6 +20,7 @@ type Action struct {
 	Node       ActionNode  `json:"node"`
 	Operations []Operation `json:"operations"`
 	Status     string      `json:"status"`
+	Duration   *Duration   `json:"duration,omitempty"`
 }
 
 type ActionNode struct {
@@ -35,6 +39,7 @@ type Operation struct {
 type OperationMeta struct {
 	Type    string `json:"type"`
 	Command string `json:"command,omitempty"`
+	Hash    string `json:"hash,omitempty"`
 }
 
 type TargetIdentity struct {
@@ -42,28 +47,12 @@ type TargetIdentity struct {
 	Project string
 }
 
-func coreDebug(message string) {
-	fmt.Printf("::debug::%s\n", message)
-}
-
-func coreWarning(message string) {
-	fmt.Printf("::warning::%s\n", message)
-}
-
-func coreStartGroup(title string) {
-	fmt.Printf("::group::%s\n", title)
-}
-
-func coreEndGroup() {
-	fmt.Println("::endgroup::")
-}
-
-func loadReport(workspaceRoot string) (*RunReport, error) {
+func loadReport(dialect Dialect, workspaceRoot string) (*RunReport, error) {
 	for _, fileName := range []string{"ciReport.json", "runReport.json"} {
 		localPath := filepath.Join(".moon", "cache", fileName)
 		reportPath := filepath.Join(workspaceRoot, localPath)
 
-		coreDebug(fmt.Sprintf("Finding run report at %s", localPath))
+		dialect.Debug(fmt.Sprintf("Finding run report at %s", localPath))
 
 		exists, err := fileExists(reportPath)
 		if err != nil {
@@ -71,78 +60,251 @@ func loadReport(workspaceRoot string) (*RunReport, error) {
 		}
 
 		if exists {
-			coreDebug("Found!")
-			data, err := os.ReadFile(reportPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read report file %s: %w", reportPath, err)
-			}
-
-			var report RunReport
-			if err := json.Unmarshal(data, &report); err != nil {
-				return nil, fmt.Errorf("failed to parse json report %s: %w", reportPath, err)
-			}
-			return &report, nil
+			dialect.Debug("Found!")
+			return readReportFile(reportPath)
 		}
 	}
 	return nil, nil
 }
 
+// readReportPath reads a report file, or finds one inside a directory such as
+// a downloaded artifact.
+func readReportPath(path string) (*RunReport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		if path, err = findReportFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return readReportFile(path)
+}
+
+func readReportFile(reportPath string) (*RunReport, error) {
+	data, err := os.ReadFile(filepath.Clean(reportPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report file %s: %w", reportPath, err)
+	}
+
+	var report RunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse json report %s: %w", reportPath, err)
+	}
+	return &report, nil
+}
+
 func main() {
-	root, err := os.Getwd()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "comment":
+			runComment(os.Args[2:])
+			return
+		}
+	}
+
+	config, err := parseConfig(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to get current working directory: %v", err)
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	report, err := loadReport(root)
+	code, err := run(config, os.Stdout)
+	if err != nil {
+		log.Fatalf("Failed to report moon run: %v", err)
+	}
+	os.Exit(code)
+}
+
+// run writes the report to config.Output and the CI system's workflow
+// commands to stdout, so secret masks never end up in a report file.
+func run(config Config, stdout io.Writer) (int, error) {
+	out, closeOutput, err := openOutput(config.Output, stdout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open output: %w", err)
+	}
+	defer closeOutput()
+
+	format := resolveFormat(config.Format, out)
+	masker := NewMasker(config.MaskEnv, os.Environ(), nil)
+	dialect := newDialect(detectDialect(config.Dialect), DialectOutput{
+		Commands: &maskingWriter{out: stdout, masker: masker},
+		Report:   &maskingWriter{out: out, masker: masker},
+		Masks:    stdout,
+		Color:    format == FormatANSI,
+	})
+	masker.AnnounceTo(dialect.Mask)
+	renderer := newRenderer(format, &maskingWriter{out: out, masker: masker}, dialect)
+
+	report, err := findReport(dialect, config)
 	if err != nil {
-		log.Fatalf("Failed to load run report: %v", err)
+		return 0, fmt.Errorf("failed to load run report: %w", err)
 	}
 
 	if report == nil {
-		coreWarning("Run report does not exist, has `moon ci` or `moon run` ran?")
-		return
+		dialect.Warning("Run report does not exist, has `moon ci` or `moon run` ran? Pass -report or set " + envName("report") + " to read one from elsewhere.")
+		return config.FailOn.exitCode(OutcomeMissing), nil
+	}
+
+	failureLogs, err := findFailureLogs(config.WorkspaceRoot)
+	if err != nil {
+		dialect.Warning(err.Error())
+	}
+
+	summary := renderReport(renderer, dialect, report, config, masker, failureLogs)
+
+	var previous *RunReport
+	if config.PreviousReport != "" {
+		if previous, err = readReportPath(config.PreviousReport); err != nil {
+			dialect.Warning(fmt.Sprintf("Ignoring the previous run: %v", err))
+		}
+	}
+
+	cache := analyzeCache(report, previous)
+	printCacheReport(renderer, cache)
+	if err := appendGitHubOutput(config.GitHubOutput, func(out io.Writer) { writeCacheOutputs(out, cache) }); err != nil {
+		dialect.Warning(err.Error())
+	}
+
+	var comparison Comparison
+	if previous != nil {
+		comparison = compareReports(previous, report, config.Regression)
+		printComparison(renderer, comparison)
+	}
+
+	err = appendStepSummary(config.StepSummary, masker, func(out io.Writer) {
+		fmt.Fprintf(out, "**moon run:** %s\n\n", summary)
+		writeComparisonSummary(out, comparison)
+		writeFailureSummary(out, failureLogs)
+	})
+	if err != nil {
+		dialect.Warning(err.Error())
+	}
+
+	if config.SARIF != "" {
+		if err := writeSARIFFile(config.SARIF, report, config.WorkspaceRoot, masker); err != nil {
+			dialect.Warning(err.Error())
+		}
+	}
+
+	if config.CommentFile != "" {
+		if err := writeCommentFile(config.CommentFile, config.CommentLabel, report, config, masker); err != nil {
+			dialect.Warning(err.Error())
+		}
+	}
+
+	return config.FailOn.exitCode(summary.Outcome()), nil
+}
+
+func findReport(dialect Dialect, config Config) (*RunReport, error) {
+	if config.ReportPath != "" {
+		dialect.Debug(fmt.Sprintf("Reading run report from %s", config.ReportPath))
+		return readReportFile(config.ReportPath)
+	}
+	return loadReport(dialect, config.WorkspaceRoot)
+}
+
+// openOutput returns stdout for "-" and a created file otherwise.
+func openOutput(path string, stdout io.Writer) (io.Writer, func(), error) {
+	if path == "-" {
+		return stdout, func() {}, nil
+	}
+
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", path, err)
+		}
+	}, nil
+}
+
+func renderReport(renderer Renderer, dialect Dialect, report *RunReport, config Config, masker *Masker, failureLogs []FailureLog) Summary {
+	printActions(renderer, report, config, masker, failureLogs)
+
+	summary := summarize(report)
+	printSummary(renderer, dialect, summary)
+	if config.Logs.ArtifactDir != "" {
+		renderer.Line(fmt.Sprintf("Full task logs: %s", config.Logs.ArtifactDir))
+	}
+	return summary
+}
+
+func printActions(renderer Renderer, report *RunReport, config Config, masker *Masker, failureLogs []FailureLog) {
+	projects, err := loadProjectDirs(config.WorkspaceRoot)
+	if err != nil {
+		log.Printf("Warning: could not read moon projects, reproduction commands use project ids as directories: %v", err)
 	}
 
 	for _, action := range report.Actions {
-		if action.Node.Action != "run-task" {
+		if action.Node.Action != "run-task" || !config.shows(action.Status) {
 			continue
 		}
 
 		targetIdentity := parseTarget(action.Node.Params.Target)
 		target := fmt.Sprintf("%s:%s", targetIdentity.Project, targetIdentity.Task)
+		title := fmt.Sprintf("%s %s", renderer.Badge(action.Status), renderer.Bold(target))
+
+		if !config.expands(action.Status) {
+			renderer.Line(title)
+			continue
+		}
 
 		command, _ := commandOf(action)
-		stdout, stderr, err := readStatus(root, targetIdentity)
+		output, err := readStatus(config.WorkspaceRoot, targetIdentity, config.Logs, masker)
 		if err != nil {
 			log.Printf("Warning: could not read status for target %s: %v", target, err)
 			continue
 		}
 
-		hasStdout := strings.TrimSpace(stdout) != ""
-		hasStderr := strings.TrimSpace(stderr) != ""
-
-		badge, ok := statusBadges[action.Status]
-		if !ok {
-			badge = action.Status // fallback
+		if output.GoTests != nil {
+			output.Stdout = output.GoTests.Output
 		}
 
-		coreStartGroup(fmt.Sprintf("%s %s", badge, bold(target)))
+		hasStdout := strings.TrimSpace(output.Stdout) != ""
+		hasStderr := strings.TrimSpace(output.Stderr) != ""
+
+		renderer.StartGroup(title)
 
 		if command != "" {
-			fmt.Println(blue(fmt.Sprintf("$ %s", command)))
+			renderer.Command(command)
 		}
 
 		if hasStdout {
-			fmt.Println(stdBadges.out)
-			fmt.Println(stdout)
+			renderer.Output(StreamStdout, output.Stdout)
 		}
 
 		if hasStderr {
-			fmt.Println(stdBadges.err)
-			fmt.Println(stderr)
+			renderer.Output(StreamStderr, output.Stderr)
 		}
 
-		coreEndGroup()
+		if isFailedStatus(action.Status) {
+			taskFailureLogs := failureLogsFor(failureLogs, targetIdentity, command)
+			printFailureLogs(renderer, taskFailureLogs, config.Logs)
+			printReproduction(renderer, reproductionCommands(targetIdentity, projects, command, output.GoTests, taskFailureLogs))
+		}
+
+		renderer.EndGroup()
+
+		if output.GoTests != nil {
+			printGoTests(renderer, target, output.GoTests, config)
+		}
+	}
+}
+
+func printSummary(renderer Renderer, dialect Dialect, summary Summary) {
+	renderer.Line(renderer.Bold(summary.String()))
+
+	if summary.Outcome() == OutcomeFailed {
+		dialect.Error(fmt.Sprintf("%d task(s) failed: %s", summary.Failed, strings.Join(summary.Failing, ", ")))
 	}
 }
 
@@ -163,34 +325,14 @@ func commandOf(action Action) (string, bool) {
 	return "", false
 }
 
-func readStatus(workspaceRoot string, identity TargetIdentity) (string, string, error) {
-	statusDir := filepath.Join(workspaceRoot, ".moon", "cache", "states", identity.Project, identity.Task)
-	stdoutPath := filepath.Join(statusDir, "stdout.log")
-	stderrPath := filepath.Join(statusDir, "stderr.log")
-
-	readLogFile := func(path string) (string, error) {
-		exists, err := fileExists(path)
-		if err != nil || !exists {
-			return "", err
-		}
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return "", err
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
-		return string(content), nil
 	}
-
-	stdout, err := readLogFile(stdoutPath)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read stdout log: %w", err)
-	}
-
-	stderr, err := readLogFile(stderrPath)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read stderr log: %w", err)
-	}
-
-	return stdout, stderr, nil
+	return items
 }
 
 func fileExists(path string) (bool, error) {
@@ -203,35 +345,3 @@ func fileExists(path string) (bool, error) {
 	}
 	return false, err
 }
-
-var statusBadges = map[string]string{
-	"running":              bgGreen(" RUNNING "),
-	"passed":               bgGreen(" PASS "),
-	"failed":               bgRed(" FAIL "),
-	"timed-out":            bgRed(" TIMED OUT "),
-	"aborted":              bgRed(" ABORTED "),
-	"invalid":              bgRed(" INVALID "),
-	"failed-and-abort":     bgRed(" FAILED AND ABORT "),
-	"skipped":              bgBlue(" SKIP "),
-	"cached":               bgBlue(" CACHED "),
-	"cached-from-remote": bgBlue(" REMOTE CACHED "),
-}
-
-type stdBadgeType struct {
-	out string
-	err string
-}
-
-var stdBadges = stdBadgeType{
-	out: bgDarkGray(fmt.Sprintf("　%s STDOUT　", green("⏺"))),
-	err: bgDarkGray(fmt.Sprintf("　%s STDERR　", red("⏺"))),
-}
-
-func bgGreen(text string) string   { return fmt.Sprintf("\u001b[42m%s\u001b[49m", text) }
-func bgRed(text string) string     { return fmt.Sprintf("\u001b[41m%s\u001b[49m", text) }
-func bgBlue(text string) string    { return fmt.Sprintf("\u001b[44m%s\u001b[49m", text) }
-func bgDarkGray(text string) string { return fmt.Sprintf("\u001b[48;5;236m%s\u001b[49m", text) }
-func bold(text string) string      { return fmt.Sprintf("\u001b[1m%s\u001b[22m", text) }
-func green(text string) string     { return fmt.Sprintf("\u001b[32m%s\u001b[39m", text) }
-func red(text string) string       { return fmt.Sprintf("\u001b[31m%s\u001b[39m", text) }
-func blue(text string) string      { return fmt.Sprintf("\u001b[34m%s\u001b[39m", text) }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+func fixture(name string) string {
+	return filepath.Join("testdata", name)
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("Failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		t.Fatalf("Failed to read golden file %s (run go test -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output does not match %s (run go test -update to accept it)\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   TargetIdentity
+	}{
+		{target: "toml:test", want: TargetIdentity{Project: "toml", Task: "test"}},
+		{target: "workspace:lint", want: TargetIdentity{Project: "workspace", Task: "lint"}},
+		{target: "app:build:release", want: TargetIdentity{Project: "app", Task: "build:release"}},
+		{target: "build", want: TargetIdentity{Project: "unknown", Task: "unknown"}},
+		{target: "", want: TargetIdentity{Project: "unknown", Task: "unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := parseTarget(tt.target); got != tt.want {
+				t.Errorf("parseTarget(%q) = %+v, want %+v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions int
+		missing bool
+		wantErr string
+	}{
+		{name: "statuses", actions: 11},
+		{name: "runreport", actions: 1},
+		{name: "empty", missing: true},
+		{name: "malformed", wantErr: "failed to parse json report"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := loadReport(newDialect(DialectPlain, DialectOutput{}), fixture(tt.name))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadReport() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadReport() error = %v", err)
+			}
+			if tt.missing {
+				if report != nil {
+					t.Fatalf("loadReport() = %+v, want nil", report)
+				}
+				return
+			}
+			if report == nil || len(report.Actions) != tt.actions {
+				t.Fatalf("loadReport() = %+v, want %d actions", report, tt.actions)
+			}
+		})
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	tests := []struct {
+		fixture string
+		format  Format
+		dialect DialectName
+	}{
+		{fixture: "statuses", format: FormatANSI, dialect: DialectGitHub},
+		{fixture: "statuses", format: FormatPlain, dialect: DialectGitHub},
+		{fixture: "statuses", format: FormatANSI, dialect: DialectGitLab},
+		{fixture: "statuses", format: FormatPlain, dialect: DialectGitLab},
+		{fixture: "statuses", format: FormatPlain, dialect: DialectPlain},
+		{fixture: "statuses", format: FormatMarkdown, dialect: DialectGitHub},
+		{fixture: "runreport", format: FormatPlain, dialect: DialectGitHub},
+		{fixture: "missing-states", format: FormatPlain, dialect: DialectGitHub},
+		{fixture: "no-colon", format: FormatPlain, dialect: DialectGitHub},
+		{fixture: "gotest", format: FormatPlain, dialect: DialectGitHub},
+		{fixture: "gotest", format: FormatMarkdown, dialect: DialectGitHub},
+	}
+
+	for _, tt := range tests {
+		name := strings.Join([]string{tt.fixture, string(tt.format), string(tt.dialect)}, ".")
+		t.Run(name, func(t *testing.T) {
+			report, err := loadReport(newDialect(DialectPlain, DialectOutput{}), fixture(tt.fixture))
+			if err != nil || report == nil {
+				t.Fatalf("loadReport() = %v, %v", report, err)
+			}
+
+			var out bytes.Buffer
+			dialect := newDialect(tt.dialect, DialectOutput{Commands: &out, Report: &out, Masks: &out, Color: tt.format == FormatANSI})
+			if gitlab, ok := dialect.(*gitlabDialect); ok {
+				gitlab.now = func() time.Time { return time.Unix(1700000000, 0) }
+			}
+
+			failureLogs, err := findFailureLogs(fixture(tt.fixture))
+			if err != nil {
+				t.Fatalf("findFailureLogs() error = %v", err)
+			}
+
+			config := Config{WorkspaceRoot: fixture(tt.fixture), IncludePassing: true}
+			renderReport(newRenderer(tt.format, &out, dialect), dialect, report, config, nil, failureLogs)
+			assertGolden(t, name, out.Bytes())
+		})
+	}
+}
+
+func TestRenderReportFiltersGoTests(t *testing.T) {
+	report, err := loadReport(newDialect(DialectPlain, DialectOutput{}), fixture("gotest"))
+	if err != nil || report == nil {
+		t.Fatalf("loadReport() = %v, %v", report, err)
+	}
+
+	var out bytes.Buffer
+	dialect := newDialect(DialectGitHub, DialectOutput{Commands: &out, Report: &out})
+	config := Config{WorkspaceRoot: fixture("gotest"), Expand: map[string]bool{"failed": true}}
+	renderReport(newRenderer(FormatPlain, &out, dialect), dialect, report, config, nil, nil)
+
+	got := out.String()
+	for _, hidden := range []string{"TestPlugins/helm", "TestPlugins/zizmor"} {
+		if strings.Contains(got, hidden) {
+			t.Errorf("output shows %s with include-passing off:\n%s", hidden, got)
+		}
+	}
+	if !strings.Contains(got, "::group::[FAIL] toml:test › TestPlugins/trivy") {
+		t.Errorf("output does not expand the failed test:\n%s", got)
+	}
+	if !strings.Contains(got, "[ABORTED] toml:test › TestPlugins/hang") || strings.Contains(got, "::group::[ABORTED]") {
+		t.Errorf("output does not list the aborted test without expanding it:\n%s", got)
+	}
+}
+
+func TestRunKeepsWorkflowCommandsOutOfOutputFile(t *testing.T) {
+	const secret = "s3cr3t-deploy-token"
+	t.Setenv("DEPLOY_SECRET", secret)
+
+	output := filepath.Join(t.TempDir(), "report.txt")
+	config, err := parseConfig([]string{
+		"-workspace", fixture("statuses"),
+		"-output", output,
+		"-dialect", string(DialectGitHub),
+		"-format", string(FormatPlain),
+		"-mask-env", "DEPLOY_SECRET",
+		"-fail-on", string(FailNever),
+	}, func(string) string { return "" }, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := run(config, &stdout); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	report, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(report, []byte(secret)) || bytes.Contains(report, []byte("::add-mask::")) {
+		t.Errorf("report file contains a secret or mask command:\n%s", report)
+	}
+	if !bytes.Contains(report, []byte("::group::")) {
+		t.Errorf("report file has no task groups:\n%s", report)
+	}
+	if !strings.Contains(stdout.String(), "::add-mask::"+secret+"\n") {
+		t.Errorf("stdout = %q, want the mask command for the secret", stdout.String())
+	}
+}
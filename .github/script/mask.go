@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const redacted = "***"
+
+// minSecretLength keeps short values such as "1" or "true" from being masked
+// everywhere they appear in a log.
+const minSecretLength = 6
+
+var defaultMaskEnv = []string{"GITHUB_TOKEN", "GH_TOKEN", "*TOKEN*", "*SECRET*", "*PASSWORD*", "*API_KEY*"}
+
+// tokenPatterns match credentials that may be printed without coming from a
+// configured environment variable. The last capture group is the secret.
+var tokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,})\b`),
+	regexp.MustCompile(`\b(github_pat_[A-Za-z0-9_]{22,})\b`),
+	// Only an Authorization header carries a bearer token; prose such as
+	// "bearer authentication" must not be masked for the rest of the job.
+	regexp.MustCompile(`(?i)(authorization:\s*bearer\s+)([A-Za-z0-9\-._~+/]{20,}=*)`),
+}
+
+// Masker redacts secrets from task output. A nil Masker leaves text unchanged.
+type Masker struct {
+	values   []string // secrets as found, for announcing
+	secrets  []string // values and their lines, longest first
+	announce func(string)
+	known    map[string]bool
+}
+
+// NewMasker collects secrets from environ entries whose name matches one of
+// the patterns, and announces each value so the CI system masks it too.
+func NewMasker(patterns, environ []string, announce func(string)) *Masker {
+	masker := &Masker{announce: announce, known: map[string]bool{}}
+
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || len(value) < minSecretLength || !matchesAny(name, patterns) {
+			continue
+		}
+		masker.addSecret(value)
+	}
+	return masker
+}
+
+// AnnounceTo sends every secret collected so far, and each one found later, to
+// announce. It lets the masker exist before the dialect that announces.
+func (m *Masker) AnnounceTo(announce func(string)) {
+	m.announce = announce
+	for _, value := range m.values {
+		announce(value)
+	}
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// addSecret remembers value and announces it. Logs are redacted a line at a
+// time, so each line of a multi-line value is remembered on its own as well;
+// the whole value is still replaced first where it appears in one piece.
+func (m *Masker) addSecret(value string) {
+	if m.known[value] {
+		return
+	}
+	m.known[value] = true
+	m.values = append(m.values, value)
+	m.secrets = append(m.secrets, value)
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimRight(line, "\r"); len(line) >= minSecretLength && !m.known[line] {
+			m.known[line] = true
+			m.secrets = append(m.secrets, line)
+		}
+	}
+	sort.Slice(m.secrets, func(i, j int) bool { return len(m.secrets[i]) > len(m.secrets[j]) })
+	if m.announce != nil {
+		m.announce(value)
+	}
+}
+
+// Redact replaces every known secret and token-shaped value in text. Tokens
+// found by pattern are remembered, so later occurrences are masked as well.
+func (m *Masker) Redact(text string) string {
+	if m == nil {
+		return text
+	}
+
+	for _, pattern := range tokenPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+			if secret := match[len(match)-1]; len(secret) >= minSecretLength {
+				m.addSecret(secret)
+			}
+		}
+	}
+
+	for _, secret := range m.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	return text
+}
+
+// maskingWriter redacts each write before passing it on. Renderers write a
+// whole line or log block per call, so secrets are not split across writes.
+type maskingWriter struct {
+	out    io.Writer
+	masker *Masker
+}
+
+func (w *maskingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.masker.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
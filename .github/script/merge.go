@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PlatformReport is a run report downloaded from one matrix target.
+type PlatformReport struct {
+	Label  string
+	Report *RunReport
+}
+
+// StatusMatrix holds the status of every task on every platform, in the order
+// the platforms were given on the command line.
+type StatusMatrix struct {
+	Platforms []string
+	Targets   []string
+	Statuses  map[string]map[string]string
+}
+
+func runMerge(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := flags.String("output", "", "file to write the merged markdown to (defaults to stdout)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: action merge [-output file] label=dir [label=dir ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse merge flags: %v", err)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		log.Fatalf("merge needs at least one report directory")
+	}
+
+	reports := make([]PlatformReport, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		report, err := loadPlatformReport(arg)
+		if err != nil {
+			log.Fatalf("Failed to load report %s: %v", arg, err)
+		}
+		reports = append(reports, report)
+	}
+
+	matrix, err := mergeReports(reports)
+	if err != nil {
+		log.Fatalf("Failed to merge reports: %v", err)
+	}
+
+	if *output == "" {
+		writeMatrixMarkdown(os.Stdout, matrix)
+		return
+	}
+
+	file, err := os.Create(filepath.Clean(*output))
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *output, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", *output, err)
+		}
+	}()
+	writeMatrixMarkdown(file, matrix)
+}
+
+// loadPlatformReport parses a "label=dir" argument. Without a label the
+// directory name is used, which matches how download-artifact names folders.
+func loadPlatformReport(arg string) (PlatformReport, error) {
+	label, dir, ok := strings.Cut(arg, "=")
+	if !ok {
+		dir = arg
+		label = filepath.Base(filepath.Clean(arg))
+	}
+
+	path, err := findReportFile(dir)
+	if err != nil {
+		return PlatformReport{}, err
+	}
+
+	report, err := readReportFile(path)
+	if err != nil {
+		return PlatformReport{}, err
+	}
+	return PlatformReport{Label: label, Report: report}, nil
+}
+
+// findReportFile accepts either a directory holding the report itself or a
+// workspace root with the report under .moon/cache.
+func findReportFile(dir string) (string, error) {
+	for _, base := range []string{dir, filepath.Join(dir, ".moon", "cache")} {
+		for _, fileName := range []string{"ciReport.json", "runReport.json"} {
+			path := filepath.Join(base, fileName)
+			exists, err := fileExists(path)
+			if err != nil {
+				return "", err
+			}
+			if exists {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no ciReport.json or runReport.json found in %s", dir)
+}
+
+// mergeReports collects each platform's task statuses into one matrix. Labels
+// name the matrix columns, so two reports with the same label are an error
+// rather than one silently replacing the other.
+func mergeReports(reports []PlatformReport) (StatusMatrix, error) {
+	matrix := StatusMatrix{Statuses: map[string]map[string]string{}}
+
+	seen := map[string]bool{}
+	for _, platform := range reports {
+		if seen[platform.Label] {
+			return StatusMatrix{}, fmt.Errorf("more than one report is labelled %q; pass label=dir to tell them apart", platform.Label)
+		}
+		seen[platform.Label] = true
+		matrix.Platforms = append(matrix.Platforms, platform.Label)
+
+		for _, action := range platform.Report.Actions {
+			if action.Node.Action != "run-task" {
+				continue
+			}
+
+			target := action.Node.Params.Target
+			if _, ok := matrix.Statuses[target]; !ok {
+				matrix.Statuses[target] = map[string]string{}
+				matrix.Targets = append(matrix.Targets, target)
+			}
+			matrix.Statuses[target][platform.Label] = action.Status
+		}
+	}
+
+	sort.Strings(matrix.Targets)
+	return matrix, nil
+}
+
+// PlatformSpecificFailures lists targets that fail on some platforms but not
+// on all of the platforms they ran on.
+func (m StatusMatrix) PlatformSpecificFailures() map[string][]string {
+	failures := map[string][]string{}
+	for _, target := range m.Targets {
+		var failing []string
+		ran := 0
+		for _, platform := range m.Platforms {
+			status, ok := m.Statuses[target][platform]
+			if !ok {
+				continue
+			}
+			ran++
+			if isFailedStatus(status) {
+				failing = append(failing, platform)
+			}
+		}
+		if len(failing) > 0 && len(failing) < ran {
+			failures[target] = failing
+		}
+	}
+	return failures
+}
+
+func writeMatrixMarkdown(out io.Writer, matrix StatusMatrix) {
+	fmt.Fprintf(out, "| Task | %s |\n", strings.Join(escapeCells(matrix.Platforms), " | "))
+	fmt.Fprintf(out, "| --- |%s\n", strings.Repeat(" --- |", len(matrix.Platforms)))
+
+	for _, target := range matrix.Targets {
+		cells := make([]string, 0, len(matrix.Platforms))
+		for _, platform := range matrix.Platforms {
+			status, ok := matrix.Statuses[target][platform]
+			if !ok {
+				cells = append(cells, "—")
+				continue
+			}
+			cells = append(cells, markdownBadge(status))
+		}
+		fmt.Fprintf(out, "| `%s` | %s |\n", target, strings.Join(cells, " | "))
+	}
+
+	failures := matrix.PlatformSpecificFailures()
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "\n**Platform-specific failures**\n\n")
+	for _, target := range matrix.Targets {
+		if platforms, ok := failures[target]; ok {
+			fmt.Fprintf(out, "- `%s` fails only on %s\n", target, strings.Join(platforms, ", "))
+		}
+	}
+}
+
+func escapeCells(cells []string) []string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.ReplaceAll(cell, "|", `\|`)
+	}
+	return escaped
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func platformReport(label string, statuses ...string) PlatformReport {
+	report := &RunReport{}
+	for i := 0; i+1 < len(statuses); i += 2 {
+		report.Actions = append(report.Actions, Action{
+			Node:   ActionNode{Action: "run-task", Params: ActionParams{Target: statuses[i]}},
+			Status: statuses[i+1],
+		})
+	}
+	return PlatformReport{Label: label, Report: report}
+}
+
+func TestMergeReports(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []PlatformReport
+		want    StatusMatrix
+		wantErr bool
+	}{
+		{
+			name: "platforms keep their order and targets are sorted",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:test", "passed", "toml:lint", "failed"),
+				platformReport("macos", "toml:test", "cached"),
+			},
+			want: StatusMatrix{
+				Platforms: []string{"ubuntu", "macos"},
+				Targets:   []string{"toml:lint", "toml:test"},
+				Statuses: map[string]map[string]string{
+					"toml:lint": {"ubuntu": "failed"},
+					"toml:test": {"ubuntu": "passed", "macos": "cached"},
+				},
+			},
+		},
+		{
+			name: "actions other than tasks are ignored",
+			reports: []PlatformReport{{Label: "ubuntu", Report: &RunReport{Actions: []Action{
+				{Node: ActionNode{Action: "sync-workspace"}, Status: "passed"},
+			}}}},
+			want: StatusMatrix{Platforms: []string{"ubuntu"}, Statuses: map[string]map[string]string{}},
+		},
+		{
+			name: "duplicate labels",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:test", "passed"),
+				platformReport("ubuntu", "toml:test", "failed"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeReports(tt.reports)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeReports() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeReports() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatformSpecificFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []PlatformReport
+		want    map[string][]string
+	}{
+		{
+			name: "failing everywhere is not platform-specific",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:test", "failed"),
+				platformReport("macos", "toml:test", "timed-out"),
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "failing on some platforms",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:test", "passed"),
+				platformReport("macos", "toml:test", "failed"),
+				platformReport("windows", "toml:test", "failed"),
+			},
+			want: map[string][]string{"toml:test": {"macos", "windows"}},
+		},
+		{
+			name: "platforms that did not run the task are not counted",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:test", "failed"),
+				platformReport("macos"),
+			},
+			want: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix, err := mergeReports(tt.reports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matrix.PlatformSpecificFailures(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlatformSpecificFailures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMatrixMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []PlatformReport
+		want    string
+	}{
+		{
+			name: "all passing",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:test", "passed"),
+				platformReport("a|b", "toml:test", "passed"),
+			},
+			want: "| Task | ubuntu | a\\|b |\n" +
+				"| --- | --- | --- |\n" +
+				"| `toml:test` | 🟢 PASS | 🟢 PASS |\n",
+		},
+		{
+			name: "missing and platform-specific",
+			reports: []PlatformReport{
+				platformReport("ubuntu", "toml:lint", "passed", "toml:test", "passed"),
+				platformReport("macos", "toml:test", "failed"),
+			},
+			want: "| Task | ubuntu | macos |\n" +
+				"| --- | --- | --- |\n" +
+				"| `toml:lint` | 🟢 PASS | — |\n" +
+				"| `toml:test` | 🟢 PASS | 🔴 FAIL |\n" +
+				"\n**Platform-specific failures**\n\n" +
+				"- `toml:test` fails only on macos\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matrix, err := mergeReports(tt.reports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			writeMatrixMarkdown(&out, matrix)
+			if got := out.String(); got != tt.want {
+				t.Errorf("writeMatrixMarkdown() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
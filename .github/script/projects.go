@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectDirs maps moon project ids to their directory below the workspace
+// root, as a slash-separated path.
+type ProjectDirs map[string]string
+
+// Dir returns the directory of project. Projects missing from the workspace
+// configuration fall back to the id, or the root for the root project.
+func (p ProjectDirs) Dir(project string) string {
+	if dir, ok := p[project]; ok {
+		return dir
+	}
+	if project == "workspace" {
+		return "."
+	}
+	return project
+}
+
+// moonWorkspace is the part of .moon/workspace.yml that says where projects
+// live. Projects is a list of globs and paths, a map of ids to paths, or an
+// object with both as globs and sources.
+type moonWorkspace struct {
+	Projects yaml.Node `yaml:"projects"`
+}
+
+type moonProjectSources struct {
+	Globs   []string          `yaml:"globs"`
+	Sources map[string]string `yaml:"sources"`
+}
+
+// moonProject is the part of a project's moon.yml that can rename it.
+type moonProject struct {
+	ID string `yaml:"id"`
+}
+
+// loadProjectDirs reads the projects setting from .moon/workspace.yml below
+// root. Glob and path entries take their id from the project's moon.yml, or
+// else from the directory name, the way moon does.
+func loadProjectDirs(root string) (ProjectDirs, error) {
+	content, err := os.ReadFile(filepath.Join(root, ".moon", "workspace.yml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ProjectDirs{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var workspace moonWorkspace
+	if err := yaml.Unmarshal(content, &workspace); err != nil {
+		return nil, fmt.Errorf("failed to parse .moon/workspace.yml: %w", err)
+	}
+
+	var sources moonProjectSources
+	switch workspace.Projects.Kind {
+	case 0:
+	case yaml.SequenceNode:
+		err = workspace.Projects.Decode(&sources.Globs)
+	case yaml.MappingNode:
+		if err = workspace.Projects.Decode(&sources); err == nil && sources.Globs == nil && sources.Sources == nil {
+			err = workspace.Projects.Decode(&sources.Sources)
+		}
+	default:
+		err = errors.New("expected a list or a map")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects from .moon/workspace.yml: %w", err)
+	}
+
+	dirs := ProjectDirs{}
+	if id, err := projectID(root, "."); err == nil && id != "" {
+		dirs[id] = "."
+	}
+	for _, glob := range sources.Globs {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(glob)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid project glob %q: %w", glob, err)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.IsDir() || strings.HasPrefix(filepath.Base(match), ".") {
+				continue
+			}
+			relative, err := filepath.Rel(root, match)
+			if err != nil {
+				return nil, err
+			}
+			dir := filepath.ToSlash(relative)
+			id, err := projectID(root, dir)
+			if err != nil {
+				return nil, err
+			}
+			if id == "" {
+				id = filepath.Base(match)
+			}
+			dirs[id] = dir
+		}
+	}
+	for id, dir := range sources.Sources {
+		dirs[id] = filepath.ToSlash(filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+// projectID returns the id set in dir's moon.yml, or "" when it has none.
+func projectID(root, dir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(dir), "moon.yml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var project moonProject
+	if err := yaml.Unmarshal(content, &project); err != nil {
+		return "", fmt.Errorf("failed to parse %s/moon.yml: %w", dir, err)
+	}
+	return project.ID, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadProjectDirs(t *testing.T) {
+	tests := []struct {
+		name     string
+		projects string
+		want     ProjectDirs
+	}{
+		{
+			name:     "globs and paths",
+			projects: "projects:\n  - \"apps/*\"\n  - \"web\"\n  - \"tools/gen\"\n",
+			want:     ProjectDirs{"workspace": ".", "api": "apps/api", "web": "web", "gen": "tools/gen"},
+		},
+		{
+			name:     "ids to paths",
+			projects: "projects:\n  server: apps/api\n",
+			want:     ProjectDirs{"workspace": ".", "server": "apps/api"},
+		},
+		{
+			name:     "globs and sources",
+			projects: "projects:\n  globs: [\"tools/*\"]\n  sources:\n    server: apps/api\n",
+			want:     ProjectDirs{"workspace": ".", "gen": "tools/gen", "server": "apps/api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			files := map[string]string{
+				".moon/workspace.yml": tt.projects,
+				"moon.yml":            "id: workspace\n",
+				"apps/api/moon.yml":   "id: api\n",
+				"web/moon.yml":        "tasks: {}\n",
+				"tools/gen/main.go":   "package main\n",
+			}
+			for name, content := range files {
+				path := filepath.Join(root, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := loadProjectDirs(root)
+			if err != nil {
+				t.Fatalf("loadProjectDirs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadProjectDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProjectDirsOfThisWorkspace(t *testing.T) {
+	projects, err := loadProjectDirs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"workspace": ".", "toml": "toml", "script": ".github/script"} {
+		if got := projects.Dir(id); got != want {
+			t.Errorf("Dir(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
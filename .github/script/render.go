@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatAuto     Format = "auto"
+	FormatANSI     Format = "ansi"
+	FormatPlain    Format = "plain"
+	FormatMarkdown Format = "markdown"
+)
+
+type Stream string
+
+const (
+	StreamStdout Stream = "STDOUT"
+	StreamStderr Stream = "STDERR"
+)
+
+type Renderer interface {
+	Badge(status string) string
+	Bold(text string) string
+	StartGroup(title string)
+	EndGroup()
+	Command(command string)
+	Output(stream Stream, content string)
+	Line(text string)
+}
+
+type badgeTone int
+
+const (
+	toneSuccess badgeTone = iota
+	toneFailure
+	toneNeutral
+)
+
+type statusBadge struct {
+	label string
+	tone  badgeTone
+}
+
+var statusBadges = map[string]statusBadge{
+	"running":            {label: "RUNNING", tone: toneSuccess},
+	"passed":             {label: "PASS", tone: toneSuccess},
+	"failed":             {label: "FAIL", tone: toneFailure},
+	"timed-out":          {label: "TIMED OUT", tone: toneFailure},
+	"aborted":            {label: "ABORTED", tone: toneFailure},
+	"invalid":            {label: "INVALID", tone: toneFailure},
+	"failed-and-abort":   {label: "FAILED AND ABORT", tone: toneFailure},
+	"skipped":            {label: "SKIP", tone: toneNeutral},
+	"cached":             {label: "CACHED", tone: toneNeutral},
+	"cached-from-remote": {label: "REMOTE CACHED", tone: toneNeutral},
+}
+
+func parseFormat(value string) (Format, error) {
+	switch format := Format(strings.TrimSpace(value)); format {
+	case FormatAuto, FormatANSI, FormatPlain, FormatMarkdown:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (expected %s, %s, %s or %s)", value, FormatAuto, FormatANSI, FormatPlain, FormatMarkdown)
+	}
+}
+
+// resolveFormat picks a concrete format for auto. GitHub Actions renders ANSI
+// even though its stdout is not a terminal, so it is treated like a TTY.
+func resolveFormat(format Format, out io.Writer) Format {
+	if format != FormatAuto {
+		return format
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return FormatPlain
+	}
+	if os.Getenv("GITHUB_ACTIONS") == "true" || isTerminal(out) {
+		return FormatANSI
+	}
+	return FormatPlain
+}
+
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// newRenderer builds the renderer for format. Terminal formats delegate
+// grouping to the CI dialect; markdown uses its own <details> blocks.
+func newRenderer(format Format, out io.Writer, dialect Dialect) Renderer {
+	switch format {
+	case FormatANSI:
+		return &ansiRenderer{out: out, dialect: dialect}
+	case FormatMarkdown:
+		return &markdownRenderer{out: out}
+	default:
+		return &plainRenderer{out: out, dialect: dialect}
+	}
+}
+
+type ansiRenderer struct {
+	out     io.Writer
+	dialect Dialect
+}
+
+func (r *ansiRenderer) Badge(status string) string {
+	badge, ok := statusBadges[status]
+	if !ok {
+		return status
+	}
+
+	text := fmt.Sprintf(" %s ", badge.label)
+	switch badge.tone {
+	case toneSuccess:
+		return bgGreen(text)
+	case toneFailure:
+		return bgRed(text)
+	default:
+		return bgBlue(text)
+	}
+}
+
+func (r *ansiRenderer) Bold(text string) string { return bold(text) }
+
+func (r *ansiRenderer) StartGroup(title string) { r.dialect.StartGroup(title) }
+
+func (r *ansiRenderer) EndGroup() { r.dialect.EndGroup() }
+
+func (r *ansiRenderer) Command(command string) {
+	fmt.Fprintln(r.out, blue(fmt.Sprintf("$ %s", command)))
+}
+
+func (r *ansiRenderer) Output(stream Stream, content string) {
+	dot := green("⏺")
+	if stream == StreamStderr {
+		dot = red("⏺")
+	}
+	fmt.Fprintln(r.out, bgDarkGray(fmt.Sprintf("　%s %s　", dot, stream)))
+	fmt.Fprintln(r.out, content)
+}
+
+func (r *ansiRenderer) Line(text string) { fmt.Fprintln(r.out, text) }
+
+type plainRenderer struct {
+	out     io.Writer
+	dialect Dialect
+}
+
+func (r *plainRenderer) Badge(status string) string {
+	badge, ok := statusBadges[status]
+	if !ok {
+		return fmt.Sprintf("[%s]", status)
+	}
+	return fmt.Sprintf("[%s]", badge.label)
+}
+
+func (r *plainRenderer) Bold(text string) string { return text }
+
+func (r *plainRenderer) StartGroup(title string) { r.dialect.StartGroup(title) }
+
+func (r *plainRenderer) EndGroup() { r.dialect.EndGroup() }
+
+func (r *plainRenderer) Command(command string) { fmt.Fprintf(r.out, "$ %s\n", command) }
+
+func (r *plainRenderer) Output(stream Stream, content string) {
+	fmt.Fprintf(r.out, "--- %s ---\n", stream)
+	fmt.Fprintln(r.out, content)
+}
+
+func (r *plainRenderer) Line(text string) { fmt.Fprintln(r.out, text) }
+
+type markdownRenderer struct {
+	out io.Writer
+}
+
+func (r *markdownRenderer) Badge(status string) string { return markdownBadge(status) }
+
+func markdownBadge(status string) string {
+	badge, ok := statusBadges[status]
+	if !ok {
+		return fmt.Sprintf("<code>%s</code>", status)
+	}
+
+	switch badge.tone {
+	case toneSuccess:
+		return "🟢 " + badge.label
+	case toneFailure:
+		return "🔴 " + badge.label
+	default:
+		return "🔵 " + badge.label
+	}
+}
+
+func (r *markdownRenderer) Bold(text string) string { return fmt.Sprintf("<strong>%s</strong>", text) }
+
+func (r *markdownRenderer) StartGroup(title string) {
+	fmt.Fprintf(r.out, "<details>\n<summary>%s</summary>\n\n", title)
+}
+
+func (r *markdownRenderer) EndGroup() { fmt.Fprint(r.out, "</details>\n\n") }
+
+func (r *markdownRenderer) Command(command string) {
+	fmt.Fprintf(r.out, "```sh\n$ %s\n```\n\n", command)
+}
+
+func (r *markdownRenderer) Output(stream Stream, content string) {
+	fence := markdownFence(content)
+	fmt.Fprintf(r.out, "**%s**\n\n%stext\n%s\n%s\n\n", stream, fence, strings.TrimRight(content, "\n"), fence)
+}
+
+func (r *markdownRenderer) Line(text string) { fmt.Fprintf(r.out, "%s\n\n", text) }
+
+// markdownFence returns a backtick fence longer than any run of backticks in
+// content, so task output cannot close the code block early.
+func markdownFence(content string) string {
+	longest, current := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+			continue
+		}
+		current = 0
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func bgGreen(text string) string    { return fmt.Sprintf("\u001b[42m%s\u001b[49m", text) }
+func bgRed(text string) string      { return fmt.Sprintf("\u001b[41m%s\u001b[49m", text) }
+func bgBlue(text string) string     { return fmt.Sprintf("\u001b[44m%s\u001b[49m", text) }
+func bgDarkGray(text string) string { return fmt.Sprintf("\u001b[48;5;236m%s\u001b[49m", text) }
+func bold(text string) string       { return fmt.Sprintf("\u001b[1m%s\u001b[22m", text) }
+func green(text string) string      { return fmt.Sprintf("\u001b[32m%s\u001b[39m", text) }
+func red(text string) string        { return fmt.Sprintf("\u001b[31m%s\u001b[39m", text) }
+func blue(text string) string       { return fmt.Sprintf("\u001b[34m%s\u001b[39m", text) }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBadgesCoverEveryStatus(t *testing.T) {
+	dialect := newDialect(DialectPlain, DialectOutput{})
+	renderers := map[Format]Renderer{
+		FormatANSI:     newRenderer(FormatANSI, io.Discard, dialect),
+		FormatPlain:    newRenderer(FormatPlain, io.Discard, dialect),
+		FormatMarkdown: newRenderer(FormatMarkdown, io.Discard, dialect),
+	}
+
+	for format, renderer := range renderers {
+		for status, badge := range statusBadges {
+			if got := renderer.Badge(status); !strings.Contains(got, badge.label) {
+				t.Errorf("%s Badge(%q) = %q, want it to contain %q", format, status, got, badge.label)
+			}
+		}
+		if got := renderer.Badge("exploded"); !strings.Contains(got, "exploded") {
+			t.Errorf("%s Badge(\"exploded\") = %q, want the raw status as fallback", format, got)
+		}
+	}
+}
+
+func TestMarkdownFence(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "plain output", want: "```"},
+		{content: "uses `code`", want: "```"},
+		{content: "```go\nfmt.Println()\n```", want: "````"},
+	}
+
+	for _, tt := range tests {
+		if got := markdownFence(tt.content); got != tt.want {
+			t.Errorf("markdownFence(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reproductionCommands returns shell lines that rerun a failed task locally:
+// the moon target, the command moon executed, and for plugin tests a go test
+// invocation narrowed to the failing tests.
+func reproductionCommands(identity TargetIdentity, projects ProjectDirs, command string, goTests *GoTestRun, failureLogs []FailureLog) []string {
+	commands := []string{fmt.Sprintf("moon run %s:%s", identity.Project, identity.Task)}
+	if command != "" {
+		commands = append(commands, command)
+	}
+
+	tests := failingTestNames(goTests, failureLogs)
+	if len(tests) == 0 {
+		return commands
+	}
+
+	return append(commands, fmt.Sprintf("go -C %s test -run '%s' .", projects.Dir(identity.Project), runPattern(tests)))
+}
+
+// runPattern builds a -run pattern matching exactly the given tests. Failing
+// subtests also fail their parent, so a parent is narrowed to the subtests
+// that failed when they all share it.
+func runPattern(tests []string) string {
+	var leaves []string
+	for _, test := range tests {
+		parent := false
+		for _, other := range tests {
+			if strings.HasPrefix(other, test+"/") {
+				parent = true
+				break
+			}
+		}
+		if !parent {
+			leaves = append(leaves, test)
+		}
+	}
+
+	top, _, _ := strings.Cut(leaves[0], "/")
+	var subtests []string
+	for _, leaf := range leaves {
+		leafTop, subtest, ok := strings.Cut(leaf, "/")
+		if !ok || leafTop != top {
+			subtests = nil
+			break
+		}
+		subtests = append(subtests, subtest)
+	}
+	if subtests != nil {
+		return fmt.Sprintf("^%s$/^%s$", top, alternatives(subtests))
+	}
+
+	var tops []string
+	seen := map[string]bool{}
+	for _, leaf := range leaves {
+		leafTop, _, _ := strings.Cut(leaf, "/")
+		if !seen[leafTop] {
+			seen[leafTop] = true
+			tops = append(tops, leafTop)
+		}
+	}
+	return fmt.Sprintf("^%s$", alternatives(tops))
+}
+
+func alternatives(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "(" + strings.Join(names, "|") + ")"
+}
+
+func failingTestNames(goTests *GoTestRun, failureLogs []FailureLog) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if goTests != nil {
+		for _, test := range goTests.Tests {
+			if isFailedStatus(test.Status) {
+				add(test.Name)
+			}
+		}
+	}
+	for _, failureLog := range failureLogs {
+		add(pluginTestName(failureLog.Plugin))
+	}
+	return names
+}
+
+// pluginTestName follows the toml test layout: every plugin is a subtest of
+// TestPlugins named after its manifest.
+func pluginTestName(plugin string) string {
+	if plugin == "" {
+		return ""
+	}
+	return "TestPlugins/" + plugin
+}
+
+func printReproduction(renderer Renderer, commands []string) {
+	renderer.Line(renderer.Bold("Reproduce locally:"))
+	for _, command := range commands {
+		renderer.Command(command)
+	}
+}
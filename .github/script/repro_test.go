@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReproductionCommands(t *testing.T) {
+	goTests := &GoTestRun{Tests: []*GoTest{
+		{Name: "TestHelm", Status: "passed"},
+		{Name: "TestTrivy", Status: "failed"},
+		{Name: "TestHang", Status: "aborted"},
+	}}
+
+	tests := []struct {
+		name        string
+		identity    TargetIdentity
+		projects    ProjectDirs
+		command     string
+		goTests     *GoTestRun
+		failureLogs []FailureLog
+		want        []string
+	}{
+		{
+			name:     "plain task",
+			identity: TargetIdentity{Project: "workspace", Task: "lint"},
+			command:  "dprint check",
+			want:     []string{"moon run workspace:lint", "dprint check"},
+		},
+		{
+			name:     "go test events",
+			identity: TargetIdentity{Project: "toml", Task: "test"},
+			command:  "go test -json",
+			goTests:  goTests,
+			want:     []string{"moon run toml:test", "go test -json", "go -C toml test -run '^(TestTrivy|TestHang)$' ."},
+		},
+		{
+			name:        "testkit failure log",
+			identity:    TargetIdentity{Project: "toml", Task: "test"},
+			failureLogs: []FailureLog{{Plugin: "terraform-docs"}},
+			want:        []string{"moon run toml:test", "go -C toml test -run '^TestPlugins$/^terraform-docs$' ."},
+		},
+		{
+			name:        "events and logs agree",
+			identity:    TargetIdentity{Project: "toml", Task: "test"},
+			goTests:     &GoTestRun{Tests: []*GoTest{{Name: "TestPlugins", Status: "failed"}, {Name: "TestPlugins/trivy", Status: "failed"}}},
+			failureLogs: []FailureLog{{Plugin: "trivy"}, {Plugin: "helm"}},
+			want:        []string{"moon run toml:test", "go -C toml test -run '^TestPlugins$/^(trivy|helm)$' ."},
+		},
+		{
+			name:     "subtests of different tests",
+			identity: TargetIdentity{Project: "toml", Task: "test"},
+			goTests:  &GoTestRun{Tests: []*GoTest{{Name: "TestSync/fix", Status: "failed"}, {Name: "TestPlugins", Status: "failed"}}},
+			want:     []string{"moon run toml:test", "go -C toml test -run '^(TestSync|TestPlugins)$' ."},
+		},
+		{
+			name:     "project outside the root",
+			identity: TargetIdentity{Project: "script", Task: "test"},
+			projects: ProjectDirs{"script": ".github/script"},
+			goTests:  &GoTestRun{Tests: []*GoTest{{Name: "TestRenderReport", Status: "failed"}}},
+			want:     []string{"moon run script:test", "go -C .github/script test -run '^TestRenderReport$' ."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reproductionCommands(tt.identity, tt.projects, tt.command, tt.goTests, tt.failureLogs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reproductionCommands() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// LintFinding is one problem reported by a linter, with a path relative to
+// the workspace root.
+type LintFinding struct {
+	Rule    string
+	Level   string
+	Message string
+	Path    string
+	Line    int
+	Column  int
+}
+
+// linter recognises a lint task by its command and turns its text output
+// into findings.
+type linter struct {
+	Name           string
+	InformationURI string
+	matches        func(fields []string) bool
+	parse          func(output string, root, dir string) ([]LintFinding, error)
+}
+
+var linters = []linter{
+	{
+		Name:           "golangci-lint",
+		InformationURI: "https://golangci-lint.run",
+		matches:        subcommandOf("golangci-lint", "run"),
+		parse:          parseGolangciLint,
+	},
+	{
+		Name:           "dprint",
+		InformationURI: "https://dprint.dev",
+		matches:        subcommandOf("dprint", "check"),
+		parse:          parseDprintCheck,
+	},
+}
+
+// subcommandOf matches commands running program with subcommand, allowing
+// the program to be a module path such as go run .../golangci-lint@v2.
+func subcommandOf(program, subcommand string) func([]string) bool {
+	return func(fields []string) bool {
+		for i, field := range fields {
+			if !strings.Contains(field, program) {
+				continue
+			}
+			for _, rest := range fields[i+1:] {
+				if rest == subcommand {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+func linterFor(command string) *linter {
+	fields := strings.Fields(command)
+	for i := range linters {
+		if linters[i].matches(fields) {
+			return &linters[i]
+		}
+	}
+	return nil
+}
+
+// golangciIssue matches the default text format: file:line[:column]: message (linter).
+var golangciIssue = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?: (.+) \(([\w-]+)\)$`)
+
+// parseGolangciLint reads golangci-lint text output. Paths are relative to
+// the config file, which lives at the workspace root, or to the project for
+// older versions; whichever exists is used. The text format has no severity,
+// so levels come from the severity section of that config file.
+func parseGolangciLint(output, root, dir string) ([]LintFinding, error) {
+	severity, err := loadGolangciSeverity(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []LintFinding
+	for _, line := range strings.Split(output, "\n") {
+		match := golangciIssue.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		finding := LintFinding{
+			Rule:    match[5],
+			Message: match[4],
+			Path:    workspacePath(root, dir, match[1]),
+			Line:    lineNumber,
+			Column:  column,
+		}
+		finding.Level = sarifLevel(severity.of(finding))
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+// golangciSeverity is the severity section of a golangci-lint config. The
+// first rule matching a finding's linter, path and text sets its severity.
+type golangciSeverity struct {
+	Default string                 `yaml:"default"`
+	Rules   []golangciSeverityRule `yaml:"rules"`
+}
+
+type golangciSeverityRule struct {
+	Linters  []string `yaml:"linters"`
+	Path     string   `yaml:"path"`
+	Text     string   `yaml:"text"`
+	Severity string   `yaml:"severity"`
+}
+
+var golangciConfigNames = []string{".golangci.yml", ".golangci.yaml"}
+
+// loadGolangciSeverity reads the severity section of the golangci-lint
+// config at root. Without a config every finding has the default severity.
+func loadGolangciSeverity(root string) (golangciSeverity, error) {
+	for _, name := range golangciConfigNames {
+		content, err := os.ReadFile(filepath.Join(root, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return golangciSeverity{}, err
+		}
+
+		var config struct {
+			Severity golangciSeverity `yaml:"severity"`
+		}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return golangciSeverity{}, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		for _, rule := range config.Severity.Rules {
+			for _, pattern := range []string{rule.Path, rule.Text} {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return golangciSeverity{}, fmt.Errorf("invalid severity rule in %s: %w", name, err)
+				}
+			}
+		}
+		return config.Severity, nil
+	}
+	return golangciSeverity{}, nil
+}
+
+func (s golangciSeverity) of(finding LintFinding) string {
+	for _, rule := range s.Rules {
+		if len(rule.Linters) > 0 && !slices.Contains(rule.Linters, finding.Rule) {
+			continue
+		}
+		if rule.Path != "" && !regexp.MustCompile(rule.Path).MatchString(finding.Path) {
+			continue
+		}
+		if rule.Text != "" && !regexp.MustCompile(rule.Text).MatchString(finding.Message) {
+			continue
+		}
+		return rule.Severity
+	}
+	return s.Default
+}
+
+// sarifLevel maps a golangci-lint severity onto a SARIF level. Severities are
+// free-form, so the names golangci-lint's own SARIF and code climate outputs
+// understand are grouped:
+//
+//	error, blocker, critical, high, major   -> error
+//	warning, warn, medium, minor            -> warning
+//	info, note, hint, low                   -> note
+//	none                                    -> none
+//
+// Findings without a severity are errors, since they fail the lint task;
+// unknown names are warnings.
+func sarifLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "", "error", "blocker", "critical", "high", "major":
+		return "error"
+	case "info", "note", "hint", "low":
+		return "note"
+	case "none":
+		return "none"
+	default:
+		return "warning"
+	}
+}
+
+var dprintLine = regexp.MustCompile(`^\s*(\d+)\s*\|`)
+
+// dprintPlugins names the dprint plugin formatting each file extension in
+// .dprint.json. It is the rule id of a finding, so code scanning groups
+// unformatted files by the formatter that disagrees with them.
+var dprintPlugins = map[string]string{
+	".json":     "json",
+	".jsonc":    "json",
+	".md":       "markdown",
+	".markdown": "markdown",
+	".toml":     "toml",
+	".yaml":     "yaml",
+	".yml":      "yaml",
+}
+
+// dprintRule returns the rule id for an unformatted file.
+func dprintRule(path string) string {
+	if plugin, ok := dprintPlugins[strings.ToLower(filepath.Ext(path))]; ok {
+		return plugin + "-not-formatted"
+	}
+	return "not-formatted"
+}
+
+// parseDprintCheck reads dprint check output: a "from <file>:" header per
+// unformatted file followed by a diff whose lines are prefixed with numbers.
+func parseDprintCheck(output, root, dir string) ([]LintFinding, error) {
+	var findings []LintFinding
+	var current *LintFinding
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if path, ok := strings.CutPrefix(line, "from "); ok && strings.HasSuffix(path, ":") {
+			path = strings.TrimSuffix(path, ":")
+			findings = append(findings, LintFinding{
+				Rule:    dprintRule(path),
+				Level:   "warning",
+				Message: "File is not formatted. Run `moon run :fmt` to format it.",
+				Path:    workspacePath(root, dir, path),
+			})
+			current = &findings[len(findings)-1]
+			continue
+		}
+		if current != nil && current.Line == 0 {
+			if match := dprintLine.FindStringSubmatch(line); match != nil {
+				current.Line, _ = strconv.Atoi(match[1])
+			}
+		}
+	}
+	return findings, nil
+}
+
+// workspacePath makes a reported path relative to the workspace root.
+func workspacePath(root, dir, path string) string {
+	if filepath.IsAbs(path) {
+		if relative, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(relative, "..") {
+			return filepath.ToSlash(relative)
+		}
+		return filepath.ToSlash(path)
+	}
+	if exists, _ := fileExists(filepath.Join(root, path)); !exists {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// LintRun holds the findings of every task run by one linter.
+type LintRun struct {
+	Linter   *linter
+	Findings []LintFinding
+}
+
+// collectLintRuns parses the full output of every lint task in the report.
+// A linter whose tasks found nothing still gets a run, so uploading the file
+// closes alerts that were fixed.
+func collectLintRuns(report *RunReport, root string, masker *Masker) ([]LintRun, error) {
+	projects, err := loadProjectDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := map[string]*LintRun{}
+	for _, action := range report.Actions {
+		if action.Node.Action != "run-task" || action.Status == "skipped" {
+			continue
+		}
+		command, _ := commandOf(action)
+		tool := linterFor(command)
+		if tool == nil {
+			continue
+		}
+
+		identity := parseTarget(action.Node.Params.Target)
+		output, err := readStatus(root, identity, LogOptions{}, masker)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s output: %w", action.Node.Params.Target, err)
+		}
+
+		run, ok := runs[tool.Name]
+		if !ok {
+			run = &LintRun{Linter: tool}
+			runs[tool.Name] = run
+		}
+		text := stripANSI(output.Stdout + "\n" + output.Stderr)
+		findings, err := tool.parse(text, root, projects.Dir(identity.Project))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s output: %w", action.Node.Params.Target, err)
+		}
+		run.Findings = append(run.Findings, findings...)
+	}
+
+	var result []LintRun
+	for i := range linters {
+		if run, ok := runs[linters[i].Name]; ok {
+			result = append(result, *run)
+		}
+	}
+	return result, nil
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func writeSARIF(out io.Writer, runs []LintRun) error {
+	document := sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{}}
+	for _, run := range runs {
+		sarif := sarifRun{
+			Tool:    sarifTool{Driver: sarifDriver{Name: run.Linter.Name, InformationURI: run.Linter.InformationURI, Rules: []sarifRule{}}},
+			Results: []sarifResult{},
+		}
+
+		rules := map[string]bool{}
+		for _, finding := range run.Findings {
+			rules[finding.Rule] = true
+
+			location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: finding.Path, URIBaseID: "%SRCROOT%"}}
+			if finding.Line > 0 {
+				location.Region = &sarifRegion{StartLine: finding.Line, StartColumn: finding.Column}
+			}
+			sarif.Results = append(sarif.Results, sarifResult{
+				RuleID:    finding.Rule,
+				Level:     finding.Level,
+				Message:   sarifMessage{Text: finding.Message},
+				Locations: []sarifLocation{{PhysicalLocation: location}},
+			})
+		}
+
+		for rule := range rules {
+			sarif.Tool.Driver.Rules = append(sarif.Tool.Driver.Rules, sarifRule{ID: rule})
+		}
+		sort.Slice(sarif.Tool.Driver.Rules, func(i, j int) bool { return sarif.Tool.Driver.Rules[i].ID < sarif.Tool.Driver.Rules[j].ID })
+
+		document.Runs = append(document.Runs, sarif)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func writeSARIFFile(path string, report *RunReport, root string, masker *Masker) error {
+	runs, err := collectLintRuns(report, root, masker)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("failed to create SARIF file: %w", err)
+	}
+	if err := writeSARIF(file, runs); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write SARIF file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLinterFor(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"go run github.com/golangci/golangci-lint/v2/cmd/golangci-lint@v2.5.0 --config .golangci.yml run", "golangci-lint"},
+		{"go run github.com/golangci/golangci-lint/v2/cmd/golangci-lint@v2.5.0 --config .golangci.yml fmt", ""},
+		{"dprint check --config .dprint.json", "dprint"},
+		{"dprint fmt --config .dprint.json", ""},
+		{"go test -json", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if tool := linterFor(tt.command); tool != nil {
+			got = tool.Name
+		}
+		if got != tt.want {
+			t.Errorf("linterFor(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestParseDprintCheck(t *testing.T) {
+	root := t.TempDir()
+	output := "from " + filepath.Join(root, "docs", "guide.md") + ":\n4| -a\n4| +b\n--\n"
+
+	want := []LintFinding{{
+		Rule:    "markdown-not-formatted",
+		Level:   "warning",
+		Message: "File is not formatted. Run `moon run :fmt` to format it.",
+		Path:    "docs/guide.md",
+		Line:    4,
+	}}
+	if got, err := parseDprintCheck(output, root, "."); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDprintCheck() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	report, err := readReportFile(filepath.Join(fixture("lint"), ".moon", "cache", "ciReport.json"))
+	if err != nil {
+		t.Fatalf("readReportFile() error = %v", err)
+	}
+
+	runs, err := collectLintRuns(report, fixture("lint"), nil)
+	if err != nil {
+		t.Fatalf("collectLintRuns() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := writeSARIF(&out, runs); err != nil {
+		t.Fatalf("writeSARIF() error = %v", err)
+	}
+	assertGolden(t, "lint.sarif", out.Bytes())
+}
+
+func TestGolangciSeverity(t *testing.T) {
+	severity := golangciSeverity{
+		Default: "warning",
+		Rules: []golangciSeverityRule{
+			{Linters: []string{"gosec"}, Path: `_test\.go$`, Severity: "low"},
+			{Linters: []string{"gosec", "errcheck"}, Severity: "critical"},
+			{Text: "deprecated", Severity: "none"},
+		},
+	}
+
+	tests := []struct {
+		finding LintFinding
+		want    string
+	}{
+		{LintFinding{Rule: "gosec", Path: "toml/lock_test.go"}, "note"},
+		{LintFinding{Rule: "gosec", Path: "toml/lock.go"}, "error"},
+		{LintFinding{Rule: "errcheck", Path: "toml/lock.go"}, "error"},
+		{LintFinding{Rule: "staticcheck", Message: "SA1019: x is deprecated"}, "none"},
+		{LintFinding{Rule: "misspell"}, "warning"},
+	}
+	for _, tt := range tests {
+		if got := sarifLevel(severity.of(tt.finding)); got != tt.want {
+			t.Errorf("level of %+v = %q, want %q", tt.finding, got, tt.want)
+		}
+	}
+
+	if got := sarifLevel(golangciSeverity{}.of(LintFinding{Rule: "errcheck"})); got != "error" {
+		t.Errorf("level without a severity config = %q, want error", got)
+	}
+}
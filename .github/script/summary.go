@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Outcome string
+
+const (
+	OutcomePassed  Outcome = "passed"
+	OutcomeFailed  Outcome = "failed"
+	OutcomeMissing Outcome = "missing"
+)
+
+type FailPolicy string
+
+const (
+	FailNever            FailPolicy = "never"
+	FailOnFailure        FailPolicy = "failure"
+	FailOnFailureMissing FailPolicy = "failure-or-missing"
+)
+
+type Summary struct {
+	Passed  int
+	Failed  int
+	Cached  int
+	Skipped int
+	Failing []string
+}
+
+var failedStatuses = map[string]bool{
+	"failed":           true,
+	"timed-out":        true,
+	"aborted":          true,
+	"invalid":          true,
+	"failed-and-abort": true,
+}
+
+func isFailedStatus(status string) bool {
+	return failedStatuses[status]
+}
+
+func summarize(report *RunReport) Summary {
+	var summary Summary
+	if report == nil {
+		return summary
+	}
+
+	for _, action := range report.Actions {
+		if action.Node.Action != "run-task" {
+			continue
+		}
+		summary.add(action.Node.Params.Target, action.Status)
+	}
+	return summary
+}
+
+func (s *Summary) add(target, status string) {
+	switch {
+	case status == "passed":
+		s.Passed++
+	case status == "cached" || status == "cached-from-remote":
+		s.Cached++
+	case status == "skipped":
+		s.Skipped++
+	case isFailedStatus(status):
+		s.Failed++
+		s.Failing = append(s.Failing, target)
+	}
+}
+
+func (s Summary) Outcome() Outcome {
+	if s.Failed > 0 {
+		return OutcomeFailed
+	}
+	return OutcomePassed
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d passed, %d failed, %d cached, %d skipped", s.Passed, s.Failed, s.Cached, s.Skipped)
+}
+
+func parseFailPolicy(value string) (FailPolicy, error) {
+	switch policy := FailPolicy(strings.TrimSpace(value)); policy {
+	case FailNever, FailOnFailure, FailOnFailureMissing:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown fail policy %q (expected %s, %s or %s)", value, FailNever, FailOnFailure, FailOnFailureMissing)
+	}
+}
+
+func (p FailPolicy) exitCode(outcome Outcome) int {
+	switch {
+	case p == FailNever:
+		return 0
+	case outcome == OutcomeFailed:
+		return 1
+	case outcome == OutcomeMissing && p == FailOnFailureMissing:
+		return 1
+	default:
+		return 0
+	}
+}
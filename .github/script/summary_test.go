@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeCoversEveryStatus(t *testing.T) {
+	report := &RunReport{}
+	for status := range statusBadges {
+		report.Actions = append(report.Actions, Action{
+			Node:   ActionNode{Action: "run-task", Params: ActionParams{Target: "plugins:" + status}},
+			Status: status,
+		})
+	}
+	report.Actions = append(report.Actions, Action{Node: ActionNode{Action: "sync-workspace"}, Status: "failed"})
+
+	summary := summarize(report)
+
+	want := Summary{Passed: 1, Failed: 5, Cached: 2, Skipped: 1}
+	if summary.Passed != want.Passed || summary.Failed != want.Failed || summary.Cached != want.Cached || summary.Skipped != want.Skipped {
+		t.Errorf("summarize() = %s, want %s", summary, want)
+	}
+	if summary.Outcome() != OutcomeFailed {
+		t.Errorf("Outcome() = %s, want %s", summary.Outcome(), OutcomeFailed)
+	}
+	for _, target := range summary.Failing {
+		if !isFailedStatus(strings.TrimPrefix(target, "plugins:")) {
+			t.Errorf("Failing contains %s, which is not a failed status", target)
+		}
+	}
+}
+
+func TestFailPolicyExitCode(t *testing.T) {
+	tests := []struct {
+		policy  FailPolicy
+		outcome Outcome
+		want    int
+	}{
+		{policy: FailNever, outcome: OutcomeFailed, want: 0},
+		{policy: FailNever, outcome: OutcomeMissing, want: 0},
+		{policy: FailOnFailure, outcome: OutcomePassed, want: 0},
+		{policy: FailOnFailure, outcome: OutcomeFailed, want: 1},
+		{policy: FailOnFailure, outcome: OutcomeMissing, want: 0},
+		{policy: FailOnFailureMissing, outcome: OutcomeMissing, want: 1},
+		{policy: FailOnFailureMissing, outcome: OutcomePassed, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy)+"/"+string(tt.outcome), func(t *testing.T) {
+			if got := tt.policy.exitCode(tt.outcome); got != tt.want {
+				t.Errorf("exitCode(%s) = %d, want %d", tt.outcome, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseFailPolicy("sometimes"); err == nil {
+		t.Error("parseFailPolicy(\"sometimes\") succeeded, want error")
+	}
+}
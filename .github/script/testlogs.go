@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FailureLog is a test-logs/<plugin>_<platform>_<timestamp>_failure.log file
+// written by the toml testkit when a plugin test fails.
+type FailureLog struct {
+	Path     string
+	Project  string
+	Plugin   string
+	Platform string
+	Commands []FailureCommand
+}
+
+type FailureCommand struct {
+	Command  string
+	Duration string
+	Success  bool
+	Output   string
+	Error    string
+}
+
+// FailingCommand returns the first command that did not succeed, or the last
+// command when every recorded command passed and an after-install check failed.
+func (l FailureLog) FailingCommand() (FailureCommand, bool) {
+	for _, command := range l.Commands {
+		if !command.Success {
+			return command, true
+		}
+	}
+	if len(l.Commands) == 0 {
+		return FailureCommand{}, false
+	}
+	return l.Commands[len(l.Commands)-1], true
+}
+
+// skippedLogDirs are never searched for failure logs. Fixtures under testdata
+// hold sample logs, including this action's own, that no test run wrote.
+var skippedLogDirs = map[string]bool{".git": true, ".moon": true, "node_modules": true, "testdata": true, "vendor": true}
+
+// findFailureLogs walks the workspace for testkit failure logs. The first
+// path segment below root is taken as the owning moon project.
+func findFailureLogs(root string) ([]FailureLog, error) {
+	var logs []FailureLog
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && skippedLogDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Base(filepath.Dir(path)) != "test-logs" || !strings.HasSuffix(entry.Name(), "_failure.log") {
+			return nil
+		}
+
+		failureLog, err := readFailureLog(path)
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		failureLog.Path = filepath.ToSlash(relative)
+		failureLog.Project = "workspace"
+		if segments := strings.Split(failureLog.Path, "/"); len(segments) > 2 {
+			failureLog.Project = segments[0]
+		}
+
+		logs = append(logs, failureLog)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for test failure logs: %w", err)
+	}
+
+	sort.Slice(logs, func(i, j int) bool { return logs[i].Path < logs[j].Path })
+	return logs, nil
+}
+
+func readFailureLog(path string) (FailureLog, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return FailureLog{}, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close failure log: %v", err)
+		}
+	}()
+
+	return parseFailureLog(file)
+}
+
+// parseFailureLog reads the sections writeFailureLog emits: a header with
+// Plugin and Platform, then one "### Command N: ..." block per command with
+// **Output:** and **Error:** bodies closed by a dashed rule.
+func parseFailureLog(reader io.Reader) (FailureLog, error) {
+	var (
+		failureLog FailureLog
+		current    *FailureCommand
+		body       *strings.Builder
+	)
+
+	finish := func() {
+		if current == nil {
+			return
+		}
+		failureLog.Commands = append(failureLog.Commands, *current)
+		current, body = nil, nil
+	}
+
+	err := eachLine(reader, func(line string) error {
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(trimmed, "### Command "):
+			finish()
+			_, command, _ := strings.Cut(trimmed, ": ")
+			current = &FailureCommand{Command: command}
+		case current == nil:
+			parseFailureHeader(&failureLog, trimmed)
+		case trimmed == "**Output:**":
+			body = &strings.Builder{}
+		case trimmed == "**Error:**":
+			current.Output = strings.Trim(body.String(), "\n")
+			body = &strings.Builder{}
+		case trimmed == strings.Repeat("-", 60):
+			if body != nil {
+				current.Error = strings.Trim(body.String(), "\n")
+			}
+			finish()
+		case body != nil:
+			body.WriteString(line)
+		case strings.HasPrefix(trimmed, "## "):
+			finish()
+		case strings.HasPrefix(trimmed, "Duration: "):
+			current.Duration = strings.TrimPrefix(trimmed, "Duration: ")
+		case strings.HasPrefix(trimmed, "Success: "):
+			current.Success = strings.TrimPrefix(trimmed, "Success: ") == "true"
+		}
+		return nil
+	})
+	if err != nil {
+		return FailureLog{}, err
+	}
+	finish()
+
+	return failureLog, nil
+}
+
+func parseFailureHeader(failureLog *FailureLog, line string) {
+	if value, ok := strings.CutPrefix(line, "Plugin: "); ok && failureLog.Plugin == "" {
+		failureLog.Plugin = value
+	}
+	if value, ok := strings.CutPrefix(line, "Platform: "); ok && failureLog.Platform == "" {
+		failureLog.Platform = value
+	}
+}
+
+// failureLogsFor returns the logs a failed task should show: plugin tests run
+// from a project's test task, so logs are matched on project and test tasks.
+func failureLogsFor(logs []FailureLog, identity TargetIdentity, command string) []FailureLog {
+	if identity.Task != "test" && !strings.Contains(command, "go test") {
+		return nil
+	}
+
+	var matched []FailureLog
+	for _, failureLog := range logs {
+		if failureLog.Project == identity.Project {
+			matched = append(matched, failureLog)
+		}
+	}
+	return matched
+}
+
+func printFailureLogs(renderer Renderer, logs []FailureLog, options LogOptions) {
+	for _, failureLog := range logs {
+		renderer.Line(renderer.Bold(fmt.Sprintf("Plugin test failure: %s on %s (%s)", failureLog.Plugin, failureLog.Platform, failureLog.Path)))
+
+		command, ok := failureLog.FailingCommand()
+		if !ok {
+			continue
+		}
+		renderer.Command(command.Command)
+		renderer.Line(fmt.Sprintf("Duration: %s", command.Duration))
+		if strings.TrimSpace(command.Error) != "" {
+			renderer.Output(StreamStderr, truncateText(command.Error+"\n", options))
+		}
+	}
+}
+
+func truncateText(text string, options LogOptions) string {
+	truncated := newTruncatedLog(options)
+	_ = eachLine(strings.NewReader(text), func(line string) error {
+		truncated.add(line)
+		return nil
+	})
+	return truncated.render("")
+}
+
+// writeFailureSummary appends a markdown section listing every failure log to
+// the job summary.
+func writeFailureSummary(out io.Writer, logs []FailureLog) {
+	if len(logs) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "### Plugin test failures\n\n")
+	fmt.Fprintf(out, "| Plugin | Platform | Failing command | Duration | Log |\n")
+	fmt.Fprintf(out, "| --- | --- | --- | --- | --- |\n")
+	for _, failureLog := range logs {
+		command, _ := failureLog.FailingCommand()
+		fmt.Fprintf(out, "| %s | %s | `%s` | %s | `%s` |\n", failureLog.Plugin, failureLog.Platform, command.Command, command.Duration, failureLog.Path)
+	}
+	fmt.Fprintln(out)
+
+	for _, failureLog := range logs {
+		command, _ := failureLog.FailingCommand()
+		if strings.TrimSpace(command.Error) == "" {
+			continue
+		}
+		fence := markdownFence(command.Error)
+		fmt.Fprintf(out, "<details>\n<summary>%s stderr</summary>\n\n%stext\n%s\n%s\n\n</details>\n\n", failureLog.Plugin, fence, command.Error, fence)
+	}
+}
+
+// appendStepSummary appends to the markdown file GitHub Actions renders as
+// the job summary. An empty path disables it.
+func appendStepSummary(path string, masker *Masker, write func(io.Writer)) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open job summary: %w", err)
+	}
+	write(&maskingWriter{out: file, masker: masker})
+	return file.Close()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindFailureLogs(t *testing.T) {
+	logs, err := findFailureLogs(fixture("gotest"))
+	if err != nil {
+		t.Fatalf("findFailureLogs() error = %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("findFailureLogs() found %d logs, want 1", len(logs))
+	}
+
+	failureLog := logs[0]
+	if failureLog.Plugin != "trivy" || failureLog.Platform != "linux" || failureLog.Project != "toml" {
+		t.Errorf("failure log = %+v, want trivy on linux in project toml", failureLog)
+	}
+	if len(failureLog.Commands) != 3 {
+		t.Fatalf("parsed %d commands, want 3", len(failureLog.Commands))
+	}
+
+	command, ok := failureLog.FailingCommand()
+	if !ok || command.Command != "proto install trivy latest" || command.Duration != "1.9s" {
+		t.Errorf("FailingCommand() = %+v, want the proto install command", command)
+	}
+	if want := "Error: plugin::download::failed\n\n  × Failed to download trivy_0.67.2_Linux-64bit.tar.gz\n  ╰─▶ HTTP 404 Not Found"; command.Error != want {
+		t.Errorf("FailingCommand().Error = %q, want %q", command.Error, want)
+	}
+
+	if got := failureLogsFor(logs, TargetIdentity{Project: "toml", Task: "lint"}, "golangci-lint run"); len(got) != 0 {
+		t.Errorf("failureLogsFor(toml:lint) = %d logs, want none", len(got))
+	}
+	if got := failureLogsFor(logs, TargetIdentity{Project: "toml", Task: "test"}, "go test"); len(got) != 1 {
+		t.Errorf("failureLogsFor(toml:test) = %d logs, want 1", len(got))
+	}
+}
+
+func TestFindFailureLogsSkipsTestdata(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join(fixture("gotest"), "toml", "test-logs", "trivy_linux_2026-10-19_02-00-02_failure.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	for _, dir := range []string{"toml/test-logs", ".github/script/testdata/gotest/toml/test-logs"} {
+		path := filepath.Join(root, filepath.FromSlash(dir))
+		if err := os.MkdirAll(path, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "trivy_linux_2026-10-19_02-00-02_failure.log"), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logs, err := findFailureLogs(root)
+	if err != nil {
+		t.Fatalf("findFailureLogs() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0].Path != "toml/test-logs/trivy_linux_2026-10-19_02-00-02_failure.log" {
+		t.Errorf("findFailureLogs() = %+v, want only the log outside testdata", logs)
+	}
+}
+
+func TestWriteFailureSummary(t *testing.T) {
+	logs, err := findFailureLogs(fixture("gotest"))
+	if err != nil {
+		t.Fatalf("findFailureLogs() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	writeFailureSummary(&out, logs)
+	assertGolden(t, "gotest.failure-summary", out.Bytes())
+}
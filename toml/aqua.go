@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const aquaFormat = "aqua"
+
+const aquaSchema = "# yaml-language-server: $schema=https://raw.githubusercontent.com/aquaproj/aqua/main/json-schema/registry.json\n"
+
+// AquaRegistry is an aqua registry.yaml.
+type AquaRegistry struct {
+	Packages []AquaPackage `yaml:"packages"`
+}
+
+// AquaPackage is the subset of an aqua package definition that maps onto a
+// proto manifest. Artifact settings apply to every platform unless an
+// override for the platform replaces them.
+type AquaPackage struct {
+	Type            string `yaml:"type"`
+	RepoOwner       string `yaml:"repo_owner,omitempty"`
+	RepoName        string `yaml:"repo_name,omitempty"`
+	Name            string `yaml:"name,omitempty"`
+	Description     string `yaml:"description,omitempty"`
+	VersionPrefix   string `yaml:"version_prefix,omitempty"`
+	VersionFilter   string `yaml:"version_filter,omitempty"`
+	AquaArtifact    `yaml:",inline"`
+	FormatOverrides []AquaFormatOverride `yaml:"format_overrides,omitempty"`
+	SupportedEnvs   []string             `yaml:"supported_envs,omitempty"`
+	Overrides       []AquaOverride       `yaml:"overrides,omitempty"`
+	Rosetta2        bool                 `yaml:"rosetta2,omitempty"`
+	WindowsArmEmu   bool                 `yaml:"windows_arm_emulation,omitempty"`
+
+	// VersionConstraint decides whether this definition applies to a
+	// release; when it does not, the first matching VersionOverrides entry
+	// replaces the settings it sets.
+	VersionConstraint string        `yaml:"version_constraint,omitempty"`
+	VersionOverrides  []AquaPackage `yaml:"version_overrides,omitempty"`
+}
+
+// AquaArtifact describes how to download and unpack one platform's asset.
+type AquaArtifact struct {
+	Asset        string            `yaml:"asset,omitempty"`
+	URL          string            `yaml:"url,omitempty"`
+	Format       string            `yaml:"format,omitempty"`
+	Replacements map[string]string `yaml:"replacements,omitempty"`
+	Checksum     *AquaChecksum     `yaml:"checksum,omitempty"`
+	Files        []AquaFile        `yaml:"files,omitempty"`
+}
+
+type AquaOverride struct {
+	GOOS         string `yaml:"goos,omitempty"`
+	GOArch       string `yaml:"goarch,omitempty"`
+	AquaArtifact `yaml:",inline"`
+}
+
+type AquaFormatOverride struct {
+	GOOS   string `yaml:"goos"`
+	Format string `yaml:"format"`
+}
+
+type AquaChecksum struct {
+	Type       string `yaml:"type,omitempty"`
+	Asset      string `yaml:"asset,omitempty"`
+	URL        string `yaml:"url,omitempty"`
+	Algorithm  string `yaml:"algorithm,omitempty"`
+	FileFormat string `yaml:"file_format,omitempty"`
+	Enabled    *bool  `yaml:"enabled,omitempty"`
+}
+
+type AquaFile struct {
+	Name string `yaml:"name"`
+	Src  string `yaml:"src,omitempty"`
+}
+
+// aquaOS and aquaArch translate proto platform and architecture names to
+// the GOOS and GOARCH values aqua templates use.
+var (
+	aquaOS   = map[string]string{"linux": "linux", "macos": "darwin", "windows": "windows"}
+	aquaArch = map[string]string{"x86_64": "amd64", "aarch64": "arm64"}
+)
+
+var (
+	githubRepositoryURL = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+?)(?:\.git)?/?$`)
+	githubReleaseURL    = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/releases/download/([^/]+)/\{(download_file|checksum_file)\}$`)
+)
+
+// aquaVersion returns the template aqua renders a tag's version with, and
+// the version_prefix or version_filter that keeps the tags proto resolves.
+func aquaVersion(prefixes []string) (expression, prefix, filter string) {
+	switch {
+	case reflect.DeepEqual(prefixes, []string{"v", ""}) || reflect.DeepEqual(prefixes, []string{""}):
+		return "{{trimV .Version}}", "", ""
+	case len(prefixes) == 1:
+		return "{{trimV .SemVer}}", prefixes[0], ""
+	}
+
+	sorted := append([]string(nil), prefixes...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	expression = ".Version"
+	conditions := make([]string, 0, len(sorted))
+	for _, prefix := range sorted {
+		expression = fmt.Sprintf("trimPrefix %q (%s)", prefix, expression)
+		conditions = append(conditions, fmt.Sprintf("Version startsWith %q", prefix))
+	}
+	return "{{" + strings.Replace(expression, "(.Version)", ".Version", 1) + "}}", "", strings.Join(conditions, " || ")
+}
+
+// releaseTag returns the literal part of a URL's release tag segment before
+// the version, when the segment is one of the tag prefixes followed by the
+// version, which may keep its v.
+func releaseTag(segment string, prefixes []string) (string, bool) {
+	tag, err := url.PathUnescape(segment)
+	if err != nil || !strings.HasSuffix(tag, "{version}") {
+		return "", false
+	}
+	literal := strings.TrimSuffix(tag, "{version}")
+	for _, prefix := range prefixes {
+		if literal == prefix || literal == prefix+"v" {
+			return literal, true
+		}
+	}
+	return "", false
+}
+
+var versionFilterPrefix = regexp.MustCompile(`^Version startsWith ("(?:[^"\\]|\\.)*")$`)
+
+// errVersionFilter marks a version_filter parseVersionFilter cannot read.
+// Callers report it against the format that lacks the filter.
+var errVersionFilter = errors.New("version_filter is not a list of startsWith prefixes")
+
+// parseVersionFilter reads a version_filter of the forms exportAqua writes:
+// prefixes joined by ||, or a single negated prefix.
+func parseVersionFilter(filter string) (prefixes []string, negated bool, err error) {
+	if inner, ok := strings.CutPrefix(filter, "not ("); ok && strings.HasSuffix(inner, ")") {
+		filter, negated = strings.TrimSuffix(inner, ")"), true
+	}
+	for _, condition := range strings.Split(filter, " || ") {
+		match := versionFilterPrefix.FindStringSubmatch(strings.TrimSpace(condition))
+		if match == nil {
+			return nil, false, fmt.Errorf("%w: %q", errVersionFilter, filter)
+		}
+		prefix, err := strconv.Unquote(match[1])
+		if err != nil {
+			return nil, false, fmt.Errorf("version_filter %q: %w", filter, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if negated && len(prefixes) > 1 {
+		return nil, false, fmt.Errorf("%w: %q", errVersionFilter, filter)
+	}
+	return prefixes, negated, nil
+}
+
+// accepts reports whether aqua considers tag a release of the package,
+// given its version_prefix and version_filter.
+func (p AquaPackage) accepts(tag string) (bool, error) {
+	if !strings.HasPrefix(tag, p.VersionPrefix) {
+		return false, nil
+	}
+	if p.VersionFilter == "" {
+		return true, nil
+	}
+	prefixes, negated, err := parseVersionFilter(p.VersionFilter)
+	if err != nil {
+		return false, err
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(tag, prefix) {
+			return !negated, nil
+		}
+	}
+	return negated, nil
+}
+
+// exportAqua translates a manifest into an aqua package. Downloads from the
+// release of the repository versions are resolved from become
+// github_release packages; anything else is an http package.
+func exportAqua(file ManifestFile) (AquaPackage, []string, error) {
+	manifest := file.Manifest
+	if manifest.Install.DownloadURL == "" {
+		return AquaPackage{}, nil, fmt.Errorf("no install.download-url")
+	}
+	repository := githubRepositoryURL.FindStringSubmatch(manifest.Resolve.GitURL)
+	if repository == nil {
+		return AquaPackage{}, nil, &UnsupportedError{Format: aquaFormat, Concept: fmt.Sprintf("resolving versions from %q, which is not a GitHub repository", manifest.Resolve.GitURL)}
+	}
+	prefixes, err := tagPrefixes(aquaFormat, manifest.Resolve)
+	if err != nil {
+		return AquaPackage{}, nil, err
+	}
+
+	var notes []string
+	pkg := AquaPackage{Type: "http", RepoOwner: repository[1], RepoName: repository[2]}
+	version, prefix, filter := aquaVersion(prefixes)
+	pkg.VersionPrefix, pkg.VersionFilter = prefix, filter
+
+	sameRelease := func(template string) (string, bool) {
+		match := githubReleaseURL.FindStringSubmatch(template)
+		if match == nil || match[1] != pkg.RepoOwner || match[2] != pkg.RepoName {
+			return "", false
+		}
+		return releaseTag(match[3], prefixes)
+	}
+	if literal, ok := sameRelease(manifest.Install.DownloadURL); ok {
+		// The release URL fixes how tags are spelled, so only those tags
+		// are releases aqua can download.
+		pkg.Type = "github_release"
+		switch {
+		case literal != "":
+			version, pkg.VersionPrefix, pkg.VersionFilter = "{{trimV .SemVer}}", literal, ""
+		case pkg.VersionPrefix == "" && pkg.VersionFilter == "":
+			pkg.VersionFilter = `not (Version startsWith "v")`
+		}
+	}
+
+	replacements := map[string]string{}
+	for _, arch := range sortedKeys(manifest.Install.Arch) {
+		if _, ok := aquaArch[arch]; !ok {
+			notes = append(notes, fmt.Sprintf("install.arch %s is dropped: aqua only installs amd64 and arm64 here", arch))
+		}
+	}
+	for _, arch := range sortedKeys(aquaArch) {
+		mapped, ok := manifest.Install.Arch[arch]
+		if !ok {
+			mapped = arch
+		}
+		if goarch := aquaArch[arch]; mapped != goarch {
+			replacements[goarch] = mapped
+		}
+	}
+	if len(replacements) > 0 {
+		pkg.Replacements = replacements
+	}
+	if len(manifest.Metadata.SelfUpgradeCommands) > 0 {
+		notes = append(notes, "metadata.self-upgrade-commands are dropped: aqua has no equivalent")
+	}
+
+	vars := map[string]string{"version": version, "arch": "{{.Arch}}", "libc": "gnu"}
+	var artifacts []AquaOverride
+	for _, platform := range manifest.orderedPlatforms() {
+		goos, ok := aquaOS[platform]
+		if !ok {
+			return AquaPackage{}, nil, &UnsupportedError{Format: aquaFormat, Concept: fmt.Sprintf("platform %q", platform)}
+		}
+		config := manifest.Platform[platform]
+		if strings.Contains(config.DownloadFile+config.ChecksumFile, "{libc}") {
+			notes = append(notes, fmt.Sprintf("{libc} on %s is rendered as gnu: aqua does not choose between gnu and musl", platform))
+		}
+
+		artifact, err := aquaArtifact(pkg.Type, manifest, config, vars, sameRelease)
+		if err != nil {
+			return AquaPackage{}, nil, err
+		}
+		pkg.SupportedEnvs = append(pkg.SupportedEnvs, goos)
+		artifacts = append(artifacts, AquaOverride{GOOS: goos, AquaArtifact: artifact})
+	}
+	if len(artifacts) == 0 {
+		return AquaPackage{}, nil, fmt.Errorf("no platforms")
+	}
+
+	// The first platform is the default; the others override what differs.
+	base := artifacts[0].AquaArtifact
+	pkg.Asset, pkg.URL, pkg.Format, pkg.Checksum, pkg.Files = base.Asset, base.URL, base.Format, base.Checksum, base.Files
+	for _, artifact := range artifacts[1:] {
+		override := AquaOverride{GOOS: artifact.GOOS}
+		if artifact.Asset != base.Asset || artifact.URL != base.URL {
+			override.Asset, override.URL = artifact.Asset, artifact.URL
+		}
+		if artifact.Format != base.Format {
+			override.Format = artifact.Format
+		}
+		if !reflect.DeepEqual(artifact.Checksum, base.Checksum) {
+			override.Checksum = artifact.Checksum
+			if override.Checksum == nil {
+				disabled := false
+				override.Checksum = &AquaChecksum{Enabled: &disabled}
+			}
+		}
+		if !reflect.DeepEqual(artifact.Files, base.Files) {
+			override.Files = artifact.Files
+		}
+		if !reflect.DeepEqual(override, AquaOverride{GOOS: artifact.GOOS}) {
+			pkg.Overrides = append(pkg.Overrides, override)
+		}
+	}
+	if len(pkg.SupportedEnvs) == len(aquaOS) {
+		pkg.SupportedEnvs = nil
+	}
+	return pkg, notes, nil
+}
+
+func aquaArtifact(packageType string, manifest Manifest, config PlatformConfig, vars map[string]string, sameRelease func(string) (string, bool)) (AquaArtifact, error) {
+	var artifact AquaArtifact
+	file, err := convertTemplate(aquaFormat, config.DownloadFile, vars)
+	if err != nil {
+		return AquaArtifact{}, err
+	}
+	artifact.Format = archiveFormat(file)
+	if !manifest.unpacks() {
+		artifact.Format = "raw"
+	}
+
+	if packageType == "github_release" {
+		artifact.Asset = file
+	} else {
+		artifact.URL, err = convertTemplate(aquaFormat, manifest.Install.DownloadURL, withFiles(vars, file, ""))
+		if err != nil {
+			return AquaArtifact{}, err
+		}
+	}
+
+	if config.ChecksumFile != "" && manifest.Install.ChecksumURL != "" {
+		checksumFile, err := convertTemplate(aquaFormat, config.ChecksumFile, vars)
+		if err != nil {
+			return AquaArtifact{}, err
+		}
+		artifact.Checksum = &AquaChecksum{Type: "github_release", Asset: checksumFile, Algorithm: "sha256"}
+		if _, ok := sameRelease(manifest.Install.ChecksumURL); packageType != "github_release" || !ok {
+			checksumURL, err := convertTemplate(aquaFormat, manifest.Install.ChecksumURL, withFiles(vars, file, checksumFile))
+			if err != nil {
+				return AquaArtifact{}, err
+			}
+			artifact.Checksum = &AquaChecksum{Type: "http", URL: checksumURL, Algorithm: "sha256"}
+		}
+	}
+
+	if src := config.executablePath(); src != "" && artifact.Format != "raw" {
+		src, err = convertTemplate(aquaFormat, src, vars)
+		if err != nil {
+			return AquaArtifact{}, err
+		}
+		artifact.Files = []AquaFile{{Name: manifest.Name, Src: src}}
+	}
+	return artifact, nil
+}
+
+func withFiles(vars map[string]string, downloadFile, checksumFile string) map[string]string {
+	extended := map[string]string{"download_file": downloadFile, "checksum_file": checksumFile}
+	for key, value := range vars {
+		extended[key] = value
+	}
+	return extended
+}
+
+func exportAquaFiles(file ManifestFile) (map[string]string, []string, error) {
+	pkg, notes, err := exportAqua(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := marshalAquaRegistry(AquaRegistry{Packages: []AquaPackage{pkg}})
+	if err != nil {
+		return nil, nil, err
+	}
+	return map[string]string{file.Stem + "/registry.yaml": content}, notes, nil
+}
+
+func marshalAquaRegistry(registry AquaRegistry) (string, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(aquaSchema)
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(registry); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// AquaDownload is what an aqua package fetches for one platform and tag.
+type AquaDownload struct {
+	URL         string
+	ChecksumURL string
+	Format      string
+	Src         string
+}
+
+// aquaFuncs are the template functions aqua packages use, with sprig's
+// argument order.
+var aquaFuncs = template.FuncMap{
+	"trimV":      func(value string) string { return strings.TrimPrefix(value, "v") },
+	"trimPrefix": func(prefix, value string) string { return strings.TrimPrefix(value, prefix) },
+	"trimSuffix": func(suffix, value string) string { return strings.TrimSuffix(value, suffix) },
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"replace":    func(old, replacement, value string) string { return strings.ReplaceAll(value, old, replacement) },
+	"title": func(value string) string {
+		if value == "" {
+			return value
+		}
+		return strings.ToUpper(value[:1]) + value[1:]
+	},
+}
+
+// supports reports whether supported_envs allow goos/goarch.
+func (p AquaPackage) supports(goos, goarch string) bool {
+	if len(p.SupportedEnvs) == 0 {
+		return true
+	}
+	for _, env := range p.SupportedEnvs {
+		if env == "all" || env == goos || env == goarch || env == goos+"/"+goarch {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve applies format_overrides and the first matching override.
+func (p AquaPackage) resolve(goos, goarch string) AquaArtifact {
+	artifact := p.AquaArtifact
+	for _, override := range p.FormatOverrides {
+		if override.GOOS == goos {
+			artifact.Format = override.Format
+		}
+	}
+	for _, override := range p.Overrides {
+		if (override.GOOS != "" && override.GOOS != goos) || (override.GOArch != "" && override.GOArch != goarch) {
+			continue
+		}
+		if override.Asset != "" {
+			artifact.Asset = override.Asset
+		}
+		if override.URL != "" {
+			artifact.URL = override.URL
+		}
+		if override.Format != "" {
+			artifact.Format = override.Format
+		}
+		if override.Replacements != nil {
+			merged := map[string]string{}
+			for key, value := range artifact.Replacements {
+				merged[key] = value
+			}
+			for key, value := range override.Replacements {
+				merged[key] = value
+			}
+			artifact.Replacements = merged
+		}
+		if override.Checksum != nil {
+			artifact.Checksum = override.Checksum
+		}
+		if override.Files != nil {
+			artifact.Files = override.Files
+		}
+		break
+	}
+	return artifact
+}
+
+// Render evaluates the package's templates for goos/goarch at a release tag
+// the way aqua does. It reports false when the platform is unsupported.
+func (p AquaPackage) Render(goos, goarch, tag string) (AquaDownload, bool, error) {
+	if !p.supports(goos, goarch) {
+		return AquaDownload{}, false, nil
+	}
+	artifact := p.resolve(goos, goarch)
+
+	replace := func(value string) string {
+		if replacement, ok := artifact.Replacements[value]; ok {
+			return replacement
+		}
+		return value
+	}
+	data := map[string]string{
+		"Version": tag,
+		"SemVer":  strings.TrimPrefix(tag, p.VersionPrefix),
+		"OS":      replace(goos),
+		"Arch":    replace(goarch),
+		"Format":  artifact.Format,
+	}
+	render := func(text string) (string, error) {
+		parsed, err := template.New("aqua").Funcs(aquaFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var out strings.Builder
+		if err := parsed.Execute(&out, data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+	release := func(asset string) (string, error) {
+		rendered, err := render(asset)
+		return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", p.RepoOwner, p.RepoName, url.PathEscape(tag), rendered), err
+	}
+
+	var download AquaDownload
+	var err error
+	download.Format = artifact.Format
+	switch p.Type {
+	case "github_release":
+		download.URL, err = release(artifact.Asset)
+	case "http":
+		download.URL, err = render(artifact.URL)
+	default:
+		return AquaDownload{}, false, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("aqua package type %q", p.Type)}
+	}
+	if err != nil {
+		return AquaDownload{}, false, err
+	}
+	// Checksum and file templates can refer to the asset's name.
+	asset := path.Base(download.URL)
+	data["Asset"] = asset
+	data["AssetWithoutExt"] = strings.TrimSuffix(asset, "."+artifact.Format)
+
+	if checksum := artifact.Checksum; checksum != nil && (checksum.Enabled == nil || *checksum.Enabled) {
+		switch checksum.Type {
+		case "github_release":
+			download.ChecksumURL, err = release(checksum.Asset)
+		case "http":
+			download.ChecksumURL, err = render(checksum.URL)
+		default:
+			err = fmt.Errorf("unsupported checksum type %q", checksum.Type)
+		}
+		if err != nil {
+			return AquaDownload{}, false, err
+		}
+	}
+
+	if len(artifact.Files) > 0 {
+		download.Src, err = render(artifact.Files[0].Src)
+		if err != nil {
+			return AquaDownload{}, false, err
+		}
+	}
+	return download, true, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const asdfFormat = "asdf"
+
+// asdfVersion stands in for the version while templates are rendered, and
+// becomes ${version} in the scripts.
+const asdfVersion = "\x00version\x00"
+
+// asdfPlatforms are the platforms lib/utils.bash detects with uname.
+var asdfPlatforms = map[string]bool{"linux": true, "macos": true, "windows": true}
+
+// shellString double-quotes value for bash, turning the version placeholder
+// into a ${version} expansion.
+func shellString(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(value)
+	return `"` + strings.ReplaceAll(escaped, asdfVersion, "${version}") + `"`
+}
+
+// asdfTarget is one row of the download table in lib/utils.bash.
+type asdfTarget struct {
+	Target      Target
+	URL         string
+	ChecksumURL string
+	Bin         string
+}
+
+func asdfTargets(manifest Manifest) ([]asdfTarget, []string, error) {
+	var rows []asdfTarget
+	var notes []string
+	noted := map[string]bool{}
+	for _, target := range releaseTargets {
+		config, ok := manifest.Platform[target.Platform]
+		if !ok {
+			continue
+		}
+		arch := target.Arch
+		if mapped, ok := manifest.Install.Arch[arch]; ok {
+			arch = mapped
+		}
+		vars := map[string]string{"version": asdfVersion, "arch": arch, "libc": "gnu"}
+
+		file, err := convertTemplate(asdfFormat, config.DownloadFile, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+		row := asdfTarget{Target: target}
+		row.URL, err = convertTemplate(asdfFormat, manifest.Install.DownloadURL, withFiles(vars, file, ""))
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.ChecksumFile != "" && manifest.Install.ChecksumURL != "" {
+			checksumFile, err := convertTemplate(asdfFormat, config.ChecksumFile, vars)
+			if err != nil {
+				return nil, nil, err
+			}
+			row.ChecksumURL, err = convertTemplate(asdfFormat, manifest.Install.ChecksumURL, withFiles(vars, file, checksumFile))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		row.Bin = config.executablePath()
+		if row.Bin == "" || !manifest.unpacks() {
+			row.Bin = file[strings.LastIndex(file, "/")+1:]
+			if archiveFormat(row.Bin) == "gz" && manifest.unpacks() {
+				row.Bin = strings.TrimSuffix(row.Bin, ".gz")
+			}
+		}
+		if row.Bin, err = convertTemplate(asdfFormat, row.Bin, vars); err != nil {
+			return nil, nil, err
+		}
+		if strings.Contains(config.DownloadFile, "{libc}") && !noted[target.Platform] {
+			noted[target.Platform] = true
+			notes = append(notes, fmt.Sprintf("{libc} on %s is rendered as gnu: the plugin does not detect musl", target.Platform))
+		}
+		rows = append(rows, row)
+	}
+	return rows, notes, nil
+}
+
+// exportAsdf writes an asdf plugin, which mise installs as well: bin/list-all
+// reads tags like proto's resolver, bin/download fetches and verifies the
+// artifact, and bin/install copies the executable.
+func exportAsdf(file ManifestFile) (map[string]string, []string, error) {
+	manifest := file.Manifest
+	switch {
+	case manifest.Install.DownloadURL == "":
+		return nil, nil, fmt.Errorf("no install.download-url")
+	case manifest.Resolve.GitURL == "":
+		return nil, nil, fmt.Errorf("no resolve.git-url")
+	}
+	for _, platform := range manifest.platformNames() {
+		if !asdfPlatforms[platform] {
+			return nil, nil, &UnsupportedError{Format: asdfFormat, Concept: fmt.Sprintf("platform %q", platform)}
+		}
+	}
+	prefixes, err := tagPrefixes(asdfFormat, manifest.Resolve)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, notes, err := asdfTargets(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, arch := range sortedKeys(manifest.Install.Arch) {
+		if _, ok := aquaArch[arch]; !ok {
+			notes = append(notes, fmt.Sprintf("install.arch %s is dropped: the plugin only detects x86_64 and aarch64", arch))
+		}
+	}
+	if len(manifest.Metadata.SelfUpgradeCommands) > 0 {
+		notes = append(notes, "metadata.self-upgrade-commands are dropped: asdf has no equivalent")
+	}
+
+	dir := "asdf-" + file.Stem + "/"
+	return map[string]string{
+		dir + "bin/list-all":   asdfListAll(file.Stem, manifest.Resolve.GitURL, prefixes),
+		dir + "bin/download":   asdfDownload(manifest.unpacks()),
+		dir + "bin/install":    asdfInstall(file.Stem),
+		dir + "lib/utils.bash": asdfUtils(file.Stem, rows),
+	}, notes, nil
+}
+
+const asdfScriptHeader = "#!/usr/bin/env bash\n# Generated by `go -C toml run . export -format asdf`. Do not edit by hand.\nset -euo pipefail\n"
+
+func asdfListAll(name, gitURL string, prefixes []string) string {
+	sorted := append([]string(nil), prefixes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	var b strings.Builder
+	b.WriteString(asdfScriptHeader)
+	fmt.Fprintf(&b, "\n# Stable %s releases, oldest first, from the tags proto resolves.\n", name)
+	fmt.Fprintf(&b, "git ls-remote --tags --refs %s |\n", shellString(gitURL))
+	b.WriteString("\tsed 's|.*refs/tags/||' |\n")
+	b.WriteString("\twhile read -r tag; do\n")
+	b.WriteString("\t\tcase \"$tag\" in\n")
+	for _, prefix := range sorted {
+		if prefix == "" {
+			b.WriteString("\t\t*) echo \"$tag\" ;;\n")
+			continue
+		}
+		fmt.Fprintf(&b, "\t\t%s*) echo \"${tag#%s}\" ;;\n", shellString(prefix), shellString(prefix))
+	}
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tdone |\n")
+	b.WriteString("\tgrep -E '^[0-9]+(\\.[0-9]+)*$' |\n")
+	b.WriteString("\tsort -t. -k1,1n -k2,2n -k3,3n |\n")
+	b.WriteString("\txargs echo\n")
+	return b.String()
+}
+
+func asdfUtils(name string, rows []asdfTarget) string {
+	var b strings.Builder
+	b.WriteString("# shellcheck shell=bash\n# Generated by `go -C toml run . export -format asdf`. Do not edit by hand.\n\n")
+	b.WriteString(`case "$(uname -s)" in
+Linux) platform=linux ;;
+Darwin) platform=macos ;;
+MINGW* | MSYS* | CYGWIN*) platform=windows ;;
+*) platform=unknown ;;
+esac
+
+case "$(uname -m)" in
+x86_64 | amd64) arch=x86_64 ;;
+arm64 | aarch64) arch=aarch64 ;;
+*) arch=unknown ;;
+esac
+
+# shellcheck disable=SC2034
+version="$ASDF_INSTALL_VERSION"
+checksum_url=""
+
+`)
+	b.WriteString("case \"$platform/$arch\" in\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%s) url=%s checksum_url=%s bin=%s ;;\n", row.Target, shellString(row.URL), shellString(row.ChecksumURL), shellString(row.Bin))
+	}
+	fmt.Fprintf(&b, "*)\n\techo \"%s has no release for $platform/$arch\" >&2\n\texit 1\n\t;;\nesac\n", name)
+	return b.String()
+}
+
+const asdfFetch = asdfScriptHeader + `
+# shellcheck source=../lib/utils.bash
+source "$(dirname "$0")/../lib/utils.bash"
+
+file="$ASDF_DOWNLOAD_PATH/${url##*/}"
+curl -fsSL -o "$file" "$url"
+
+if [ -n "$checksum_url" ]; then
+	expected="$(curl -fsSL "$checksum_url" | awk -v file="${url##*/}" '$NF == file || $NF == "*" file || NF == 1 { print $1; exit }')"
+	actual="$( (sha256sum "$file" 2>/dev/null || shasum -a 256 "$file") | awk '{ print $1 }')"
+	if [ "$expected" != "$actual" ]; then
+		echo "SHA-256 of ${url##*/} is $actual, $checksum_url lists ${expected:-nothing}" >&2
+		exit 1
+	fi
+fi
+`
+
+const asdfUnpack = `
+case "$file" in
+*.tar.gz | *.tgz) tar -xzf "$file" -C "$ASDF_DOWNLOAD_PATH" && rm "$file" ;;
+*.tar.xz) tar -xJf "$file" -C "$ASDF_DOWNLOAD_PATH" && rm "$file" ;;
+*.tar.bz2) tar -xjf "$file" -C "$ASDF_DOWNLOAD_PATH" && rm "$file" ;;
+*.zip) unzip -qo "$file" -d "$ASDF_DOWNLOAD_PATH" && rm "$file" ;;
+*.gz) gunzip -f "$file" ;;
+esac
+`
+
+// asdfDownload fetches and verifies the artifact, then unpacks it unless the
+// manifest installs the download as it is.
+func asdfDownload(unpack bool) string {
+	if !unpack {
+		return asdfFetch
+	}
+	return asdfFetch + asdfUnpack
+}
+
+func asdfInstall(name string) string {
+	return asdfScriptHeader + fmt.Sprintf(`
+# shellcheck source=../lib/utils.bash
+source "$(dirname "$0")/../lib/utils.bash"
+
+exe=""
+if [ "$platform" = windows ]; then
+	exe=".exe"
+fi
+
+mkdir -p "$ASDF_INSTALL_PATH/bin"
+cp "$ASDF_DOWNLOAD_PATH/$bin" "$ASDF_INSTALL_PATH/bin/%s$exe"
+chmod +x "$ASDF_INSTALL_PATH/bin/%s$exe"
+`, name, name)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	catalogStart = "<!-- plugin-catalog:start -->"
+	catalogEnd   = "<!-- plugin-catalog:end -->"
+)
+
+var catalogHeader = []string{"Tool", "Upstream", "Platforms", "Arch mapping", "Checksum verified", "Unpack", "Minimum version"}
+
+// catalogRows describes every manifest, one row per tool.
+func catalogRows(workspace *Workspace) [][]string {
+	rows := make([][]string, 0, len(workspace.Manifests))
+	for _, manifest := range workspace.Manifests {
+		rows = append(rows, []string{
+			fmt.Sprintf("`%s`", manifest.Stem),
+			upstreamLink(manifest.Resolve.GitURL),
+			strings.Join(manifest.orderedPlatforms(), ", "),
+			archMapping(manifest.Install.Arch),
+			checksumStatus(workspace, manifest),
+			unpackMode(manifest.Install.Unpack),
+			minimumVersion(workspace.Prototools.Tools[manifest.Stem]),
+		})
+	}
+	return rows
+}
+
+func upstreamLink(gitURL string) string {
+	if gitURL == "" {
+		return "-"
+	}
+	return fmt.Sprintf("[%s](%s)", strings.TrimPrefix(gitURL, "https://github.com/"), gitURL)
+}
+
+func archMapping(arch map[string]string) string {
+	if len(arch) == 0 {
+		return "-"
+	}
+	mappings := make([]string, 0, len(arch))
+	for _, key := range sortedKeys(arch) {
+		mappings = append(mappings, fmt.Sprintf("%s → %s", key, arch[key]))
+	}
+	return strings.Join(mappings, ", ")
+}
+
+// checksumVerified reports whether proto verifies downloads on every
+// platform the manifest supports.
+func (m Manifest) checksumVerified() bool {
+	if m.Install.ChecksumURL == "" || len(m.Platform) == 0 {
+		return false
+	}
+	for _, platform := range m.Platform {
+		if platform.ChecksumFile == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// checksumStatus is "yes" when proto checks downloads against upstream's
+// checksum files, "vendored" when toml/checksums covers every supported
+// target instead, and "no" otherwise. Invalid vendored checksums count as
+// missing; sync reports them.
+func checksumStatus(workspace *Workspace, manifest ManifestFile) string {
+	if manifest.checksumVerified() {
+		return "yes"
+	}
+	if contains(workspace.Checksums, manifest.Stem) {
+		checksums, err := readVendoredChecksums(vendoredChecksumPath(filepath.Dir(manifest.Path), manifest.Stem))
+		if err == nil && checksums.covers(manifest.Manifest) {
+			return "vendored"
+		}
+	}
+	return "no"
+}
+
+// unpackMode shows the unpack setting; proto unpacks archives by default.
+func unpackMode(unpack *bool) string {
+	switch {
+	case unpack == nil:
+		return "auto"
+	case *unpack:
+		return "always"
+	default:
+		return "never"
+	}
+}
+
+func minimumVersion(constraint string) string {
+	if constraint == "" {
+		return "-"
+	}
+	if version, ok := strings.CutPrefix(constraint, ">="); ok {
+		return strings.TrimSpace(version)
+	}
+	return constraint
+}
+
+// renderTable writes a markdown table with padded columns, the layout dprint
+// formats tables to.
+func renderTable(header []string, rows [][]string) string {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell), 3)
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i, cell := range cells {
+			fmt.Fprintf(&b, " %s%s |", cell, strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(header)
+	separator := make([]string, len(widths))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	writeRow(separator)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+// updateReadme replaces the text between the catalog markers.
+func updateReadme(readme, catalog string) (string, error) {
+	start := strings.Index(readme, catalogStart)
+	end := strings.Index(readme, catalogEnd)
+	if start < 0 || end < start {
+		return "", fmt.Errorf("README.md needs %s and %s around the catalog", catalogStart, catalogEnd)
+	}
+	return readme[:start+len(catalogStart)] + "\n\n" + catalog + "\n" + readme[end:], nil
+}
+
+func runCatalog(args []string) {
+	flags := flag.NewFlagSet("catalog", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	check := flags.Bool("check", false, "fail instead of writing when README.md is out of date")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse catalog flags: %v", err)
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+
+	readmePath := filepath.Join(workspace.Root, "README.md")
+	readme, err := os.ReadFile(filepath.Clean(readmePath))
+	if err != nil {
+		log.Fatalf("Failed to read README.md: %v", err)
+	}
+
+	updated, err := updateReadme(string(readme), renderTable(catalogHeader, catalogRows(workspace)))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch {
+	case updated == string(readme):
+		fmt.Printf("README.md lists %d plugins\n", len(workspace.Manifests))
+	case *check:
+		log.Fatal(errors.New("README.md catalog is out of date; run: go -C toml run . catalog"))
+	default:
+		if err := os.WriteFile(filepath.Clean(readmePath), []byte(updated), 0o600); err != nil {
+			log.Fatalf("Failed to write README.md: %v", err)
+		}
+		fmt.Printf("Updated the catalog of %d plugins in README.md\n", len(workspace.Manifests))
+	}
+}
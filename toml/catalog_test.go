@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// vendoredSums is the body of a vendored checksums table for targets.
+func vendoredSums(targets ...string) string {
+	var body strings.Builder
+	for _, target := range targets {
+		fmt.Fprintf(&body, "%q = %q\n", target, sha256Hex(target))
+	}
+	return body.String()
+}
+
+func TestCatalogRows(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".prototools": "helm = \">=3.19.0\"\nkubectl = \"~1.34\"\n",
+		"toml/helm.toml": `name = "helm"
+[platform.linux]
+checksum-file = "helm.sha256sum"
+[platform.windows]
+checksum-file = "helm.sha256sum"
+[install]
+checksum-url = "https://get.helm.sh/{checksum_file}"
+unpack = true
+[install.arch]
+x86_64 = "amd64"
+aarch64 = "arm64"
+[resolve]
+git-url = "https://github.com/helm/helm"
+`,
+		"toml/kubectl.toml": `name = "kubectl"
+[platform.linux]
+[platform.macos]
+[install]
+unpack = false
+`,
+		"toml/checksums/kubectl.toml": "[\"1.34.1\"]\n" + vendoredSums("linux/x86_64", "linux/aarch64", "macos/x86_64", "macos/aarch64"),
+		"toml/checksums/zizmor.toml":  "[\"1.14.2\"]\n" + vendoredSums("linux/x86_64"),
+		"toml/zizmor.toml": `name = "zizmor"
+[platform.macos]
+checksum-file = "zizmor.sha256"
+[platform.linux]
+[install]
+checksum-url = "https://example.com/{checksum_file}"
+`,
+	})
+
+	workspace, err := loadWorkspace(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"`helm`", "[helm/helm](https://github.com/helm/helm)", "linux, windows", "aarch64 → arm64, x86_64 → amd64", "yes", "always", "3.19.0"},
+		{"`kubectl`", "-", "linux, macos", "-", "vendored", "never", "~1.34"},
+		{"`zizmor`", "-", "linux, macos", "-", "no", "auto", "-"},
+	}
+	if got := catalogRows(workspace); !reflect.DeepEqual(got, want) {
+		t.Errorf("catalogRows() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	got := renderTable([]string{"Tool", "A"}, [][]string{{"`kubectl`", "→"}})
+	want := "| Tool      | A   |\n| --------- | --- |\n| `kubectl` | →   |\n"
+	if got != want {
+		t.Errorf("renderTable() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUpdateReadme(t *testing.T) {
+	readme := "# Title\n\n" + catalogStart + "\nstale\n" + catalogEnd + "\n\nMore.\n"
+	got, err := updateReadme(readme, "| table |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Title\n\n" + catalogStart + "\n\n| table |\n\n" + catalogEnd + "\n\nMore.\n"
+	if got != want {
+		t.Errorf("updateReadme() = %q, want %q", got, want)
+	}
+
+	if _, err := updateReadme("# Title\n", "| table |\n"); err == nil {
+		t.Error("updateReadme() without markers should fail")
+	}
+}
+
+// TestReadmeCatalog fails when README.md was not regenerated after a
+// manifest or constraint changed.
+func TestReadmeCatalog(t *testing.T) {
+	workspace, err := loadWorkspace("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readme, err := os.ReadFile(filepath.Join("..", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := updateReadme(string(readme), renderTable(catalogHeader, catalogRows(workspace)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != string(readme) {
+		t.Error("README.md catalog is out of date; run: go -C toml run . catalog")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// checksumDir holds toml/checksums/<name>.toml for upstreams that publish no
+// checksum file, so their downloads can still be verified.
+const checksumDir = "checksums"
+
+const checksumHeader = "# SHA-256 of each release artifact, computed by `go -C toml run . checksums`.\n"
+
+// VendoredChecksums maps a version to the SHA-256 of each target's artifact,
+// keyed by "platform/arch".
+type VendoredChecksums map[string]map[string]string
+
+func vendoredChecksumPath(dir, name string) string {
+	return filepath.Join(dir, checksumDir, name+".toml")
+}
+
+// readVendoredChecksums returns no checksums when path does not exist.
+func readVendoredChecksums(path string) (VendoredChecksums, error) {
+	checksums := VendoredChecksums{}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return checksums, nil
+	}
+	if _, err := toml.DecodeFile(filepath.Clean(path), &checksums); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return checksums, checksums.validate(path)
+}
+
+func (c VendoredChecksums) validate(path string) error {
+	var problems []error
+	targets := map[string]bool{}
+	for _, target := range releaseTargets {
+		targets[target.String()] = true
+	}
+	for _, version := range c.versions() {
+		if _, ok := parseVersion(version); !ok {
+			problems = append(problems, fmt.Errorf("%s: %q is not a release version", path, version))
+		}
+		for target, sum := range c[version] {
+			if !targets[target] {
+				problems = append(problems, fmt.Errorf("%s: %s has unknown target %q", path, version, target))
+			}
+			if !isSHA256(sum) {
+				problems = append(problems, fmt.Errorf("%s: %s %s is not a SHA-256", path, version, target))
+			}
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// versions returns the vendored versions, newest first.
+func (c VendoredChecksums) versions() []string {
+	versions := make([]string, 0, len(c))
+	for version := range c {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		a, _ := parseVersion(versions[i])
+		b, _ := parseVersion(versions[j])
+		return a.Compare(b) > 0
+	})
+	return versions
+}
+
+// covers reports whether every vendored version has a checksum for each
+// target manifest supports.
+func (c VendoredChecksums) covers(manifest Manifest) bool {
+	if len(c) == 0 {
+		return false
+	}
+	for version := range c {
+		for _, target := range releaseTargets {
+			if _, supported := manifest.Platform[target.Platform]; !supported {
+				continue
+			}
+			if _, ok := c.Lookup(version, target); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Lookup returns the vendored SHA-256 of a version's artifact for target.
+func (c VendoredChecksums) Lookup(version string, target Target) (string, bool) {
+	sum, ok := c[version][target.String()]
+	return sum, ok
+}
+
+// Write renders the checksums newest version first, targets in the order
+// they are locked.
+func (c VendoredChecksums) Write(path string) error {
+	var out strings.Builder
+	out.WriteString(checksumHeader)
+	for _, version := range c.versions() {
+		fmt.Fprintf(&out, "\n[%s]\n", tomlString(version))
+		for _, target := range releaseTargets {
+			if sum, ok := c.Lookup(version, target); ok {
+				fmt.Fprintf(&out, "%s = %s\n", tomlString(target.String()), tomlString(sum))
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(path), []byte(out.String()), 0o600)
+}
+
+// mirrorURL is where a mirror serves an artifact: <mirror>/<name>/<version>/
+// followed by the rendered download file.
+func mirrorURL(mirror, name, version, file string) string {
+	return strings.TrimRight(mirror, "/") + "/" + name + "/" + version + "/" + file
+}
+
+// computeChecksums downloads a version's artifact for every supported target,
+// from upstream or, when mirror is set, from a mirror, and hashes it.
+func computeChecksums(client *http.Client, name string, manifest Manifest, version, mirror string) (map[string]string, error) {
+	sums := map[string]string{}
+	for _, target := range releaseTargets {
+		download, ok := manifest.Download(target, version)
+		if !ok {
+			continue
+		}
+		url := download.URL
+		if mirror != "" {
+			url = mirrorURL(mirror, name, version, download.File)
+		}
+		sum, err := hashURL(client, url)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", target, err)
+		}
+		sums[target.String()] = sum
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("manifest supports none of the locked targets")
+	}
+	return sums, nil
+}
+
+// verifyDownload fails when the artifact at url does not hash to want.
+func verifyDownload(hash ArtifactHasher, url, want, source string) error {
+	sum, err := hash(url)
+	if err != nil {
+		return err
+	}
+	if sum != want {
+		return fmt.Errorf("%s has SHA-256 %s, %s expects %s", url, sum, source, want)
+	}
+	return nil
+}
+
+// verifyVendored checks the artifact manifest renders for target against the
+// vendored checksum of version.
+func verifyVendored(hash ArtifactHasher, manifest Manifest, checksums VendoredChecksums, version string, target Target) error {
+	want, ok := checksums.Lookup(version, target)
+	if !ok {
+		return fmt.Errorf("no vendored checksum for %s %s; run: go -C toml run . checksums -tool %s -version %s", version, target, manifest.Name, version)
+	}
+	download, ok := manifest.Download(target, version)
+	if !ok {
+		return fmt.Errorf("manifest does not support %s", target.Platform)
+	}
+	return verifyDownload(hash, download.URL, want, "the vendored checksum")
+}
+
+func runChecksums(args []string) {
+	flags := flag.NewFlagSet("checksums", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	tool := flags.String("tool", "", "plugin to compute checksums for")
+	version := flags.String("version", "", "release to hash (defaults to the newest satisfying the .prototools constraint)")
+	mirror := flags.String("mirror", "", "mirror base URL to download from instead of upstream")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse checksums flags: %v", err)
+	}
+	if *tool == "" {
+		log.Fatalf("checksums needs -tool with the plugin name")
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+	var manifest *ManifestFile
+	for i := range workspace.Manifests {
+		if workspace.Manifests[i].Stem == *tool {
+			manifest = &workspace.Manifests[i]
+		}
+	}
+	if manifest == nil {
+		log.Fatalf("No manifest toml/%s.toml", *tool)
+	}
+
+	if *version == "" {
+		resolved, err := resolveVersion(manifest.Manifest, workspace.Prototools.Tools[*tool], gitTagLister{})
+		if err != nil {
+			log.Fatalf("Failed to resolve %s: %v", *tool, err)
+		}
+		*version = resolved.String()
+	}
+
+	sums, err := computeChecksums(defaultHTTPClient(), *tool, manifest.Manifest, *version, *mirror)
+	if err != nil {
+		log.Fatalf("Failed to compute checksums for %s %s: %v", *tool, *version, err)
+	}
+
+	path := vendoredChecksumPath(filepath.Dir(manifest.Path), *tool)
+	checksums, err := readVendoredChecksums(path)
+	if err != nil {
+		log.Fatalf("Failed to read vendored checksums: %v", err)
+	}
+	checksums[*version] = sums
+	if err := checksums.Write(path); err != nil {
+		log.Fatalf("Failed to write vendored checksums: %v", err)
+	}
+	fmt.Printf("Vendored %d checksums for %s %s in %s\n", len(sums), *tool, *version, path)
+}
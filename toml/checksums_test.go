@@ -0,0 +1,179 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVendoredChecksums(t *testing.T) {
+	sum := sha256Hex("artifact")
+	checksums := VendoredChecksums{
+		"1.9.0":  {"linux/x86_64": sum},
+		"1.10.0": {"windows/x86_64": sum, "linux/x86_64": sum},
+	}
+
+	path := vendoredChecksumPath(t.TempDir(), "tool")
+	if err := checksums.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := checksumHeader + `
+["1.10.0"]
+"linux/x86_64" = "` + sum + `"
+"windows/x86_64" = "` + sum + `"
+
+["1.9.0"]
+"linux/x86_64" = "` + sum + `"
+`
+	if string(content) != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", content, want)
+	}
+
+	read, err := readVendoredChecksums(path)
+	if err != nil || !reflect.DeepEqual(read, checksums) {
+		t.Errorf("readVendoredChecksums() = %v, %v, want %v", read, err, checksums)
+	}
+	if _, ok := read.Lookup("1.9.0", Target{"macos", "aarch64"}); ok {
+		t.Error("Lookup() found a target that is not vendored")
+	}
+	windows := Manifest{Platform: map[string]PlatformConfig{"windows": {}}}
+	if !(VendoredChecksums{"1.10.0": checksums["1.10.0"]}).covers(windows) {
+		t.Error("covers() = false, want 1.10.0 to cover windows/x86_64")
+	}
+	if checksums.covers(windows) {
+		t.Error("covers() = true, but 1.9.0 has no windows/x86_64 checksum")
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.toml")
+	writeFiles(t, filepath.Dir(invalid), map[string]string{"invalid.toml": "[\"v1\"]\n\"linux/riscv\" = \"abc\"\n"})
+	_, err = readVendoredChecksums(invalid)
+	for _, message := range []string{`"v1" is not a release version`, `unknown target "linux/riscv"`, "is not a SHA-256"} {
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("readVendoredChecksums() error = %v, want it to mention %s", err, message)
+		}
+	}
+}
+
+func TestVendoredChecksumVersions(t *testing.T) {
+	sum := sha256Hex("artifact")
+	for version, valid := range map[string]bool{"1.2.3": true, "0.11.0": true, "v1.2.3": false, "1.2.3-rc.1": false, "latest": false} {
+		err := VendoredChecksums{version: {"linux/x86_64": sum}}.validate("tool.toml")
+		if (err == nil) != valid {
+			t.Errorf("validate() with version %q = %v, want valid %t", version, err, valid)
+		}
+	}
+}
+
+const vendoredManifest = `name = "tool"
+
+[platform.linux]
+download-file = "tool-{arch}-unknown-linux-gnu.tar.gz"
+
+[platform.windows]
+download-file = "tool-{arch}-pc-windows-msvc.zip"
+
+[install]
+download-url = "%s/releases/v{version}/{download_file}"
+`
+
+func TestComputeChecksums(t *testing.T) {
+	files := map[string]string{
+		"/releases/v2.0.0/tool-x86_64-unknown-linux-gnu.tar.gz":    "linux x86_64",
+		"/releases/v2.0.0/tool-aarch64-unknown-linux-gnu.tar.gz":   "linux aarch64",
+		"/releases/v2.0.0/tool-x86_64-pc-windows-msvc.zip":         "windows x86_64",
+		"/mirror/tool/2.0.0/tool-x86_64-unknown-linux-gnu.tar.gz":  "linux x86_64",
+		"/mirror/tool/2.0.0/tool-aarch64-unknown-linux-gnu.tar.gz": "linux aarch64",
+		"/mirror/tool/2.0.0/tool-x86_64-pc-windows-msvc.zip":       "windows x86_64",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"tool.toml": strings.Replace(vendoredManifest, "%s", server.URL, 1)})
+	manifest, err := readManifest(filepath.Join(dir, "tool.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"linux/x86_64":   sha256Hex("linux x86_64"),
+		"linux/aarch64":  sha256Hex("linux aarch64"),
+		"windows/x86_64": sha256Hex("windows x86_64"),
+	}
+	for _, mirror := range []string{"", server.URL + "/mirror/"} {
+		sums, err := computeChecksums(server.Client(), "tool", manifest, "2.0.0", mirror)
+		if err != nil || !reflect.DeepEqual(sums, want) {
+			t.Errorf("computeChecksums(mirror %q) = %v, %v, want %v", mirror, sums, err, want)
+		}
+	}
+	if _, err := computeChecksums(server.Client(), "tool", manifest, "3.0.0", ""); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("computeChecksums() for a missing release error = %v", err)
+	}
+
+	checksums := VendoredChecksums{"2.0.0": want}
+	target := Target{"linux", "aarch64"}
+	if err := verifyVendored(downloadHasher(server.Client()), manifest, checksums, "2.0.0", target); err != nil {
+		t.Errorf("verifyVendored() error = %v", err)
+	}
+	files["/releases/v2.0.0/tool-aarch64-unknown-linux-gnu.tar.gz"] = "tampered"
+	if err := verifyVendored(downloadHasher(server.Client()), manifest, checksums, "2.0.0", target); err == nil || !strings.Contains(err.Error(), "the vendored checksum expects") {
+		t.Errorf("verifyVendored() with a changed artifact error = %v", err)
+	}
+	if err := verifyVendored(downloadHasher(server.Client()), manifest, checksums, "1.0.0", target); err == nil {
+		t.Error("verifyVendored() accepted a version without vendored checksums")
+	}
+	if err := verifyVendored(downloadHasher(server.Client()), manifest, checksums, "2.0.0", Target{"macos", "aarch64"}); err == nil || !strings.Contains(err.Error(), "checksums -tool tool -version 2.0.0") {
+		t.Errorf("verifyVendored() for a target without a vendored checksum error = %v", err)
+	}
+
+	// Vendored checksums stand in for a checksum file when locking.
+	locker := &Locker{Client: server.Client()}
+	download, _ := manifest.Download(target, "2.0.0")
+	if sum, err := locker.checksum(download, "2.0.0", checksums); err != nil || sum != want["linux/aarch64"] {
+		t.Errorf("Locker.checksum() = %q, %v, want the vendored checksum", sum, err)
+	}
+}
+
+func TestCheckSyncVendoredChecksums(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".prototools":                 "kubectx = \">=0.9.5\"\n\n[plugins]\nkubectx = \"file://./toml/kubectx.toml\"\n",
+		"toml/kubectx.toml":           `name = "kubectx"`,
+		"toml/testdata/kubectx.toml":  versionSpec,
+		"toml/checksums/kubectx.toml": "[\"0.9.5\"]\n\"linux/x86_64\" = \"abc\"\n",
+		"toml/checksums/gone.toml":    "",
+		lockFileName:                  "[plugins.kubectx]\nversion = \"0.9.5\"\n",
+	})
+
+	workspace, err := loadWorkspace(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, problem := range checkSync(workspace) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		"gone: vendored checksums toml/checksums/gone.toml have no manifest",
+		"kubectx: invalid vendored checksums: " + vendoredChecksumPath(filepath.Join(root, "toml"), "kubectx") + ": 0.9.5 linux/x86_64 is not a SHA-256",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkSync() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Target is a platform and proto architecture an artifact is built for.
+type Target struct {
+	Platform string
+	Arch     string
+}
+
+func (t Target) String() string {
+	return t.Platform + "/" + t.Arch
+}
+
+// releaseTargets are the targets locked and mirrored. Windows on ARM is left
+// out because most upstreams do not publish it.
+var releaseTargets = []Target{
+	{"linux", "x86_64"},
+	{"linux", "aarch64"},
+	{"macos", "x86_64"},
+	{"macos", "aarch64"},
+	{"windows", "x86_64"},
+}
+
+// Download is a manifest's artifact for one target and version, with the
+// placeholders proto substitutes filled in.
+type Download struct {
+	Target       Target
+	File         string
+	URL          string
+	ChecksumFile string
+	ChecksumURL  string
+}
+
+// Download renders the download and checksum URLs for target, or reports
+// false when the manifest does not support its platform.
+func (m Manifest) Download(target Target, version string) (Download, bool) {
+	platform, ok := m.Platform[target.Platform]
+	if !ok {
+		return Download{}, false
+	}
+
+	arch := target.Arch
+	if mapped, ok := m.Install.Arch[arch]; ok {
+		arch = mapped
+	}
+	replacer := strings.NewReplacer("{version}", version, "{arch}", arch, "{os}", target.Platform, "{libc}", "gnu")
+
+	download := Download{
+		Target:       target,
+		File:         replacer.Replace(platform.DownloadFile),
+		ChecksumFile: replacer.Replace(platform.ChecksumFile),
+	}
+	files := strings.NewReplacer("{download_file}", download.File, "{checksum_file}", download.ChecksumFile)
+	download.URL = files.Replace(replacer.Replace(m.Install.DownloadURL))
+	if download.ChecksumFile != "" && m.Install.ChecksumURL != "" {
+		download.ChecksumURL = files.Replace(replacer.Replace(m.Install.ChecksumURL))
+	}
+	return download, true
+}
+
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Minute}
+}
+
+// fetch streams a URL's body into write.
+func fetch(client *http.Client, url string, write func(io.Reader) error) error {
+	response, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.Printf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	return write(response.Body)
+}
+
+// hashURL downloads url and returns its SHA-256 in hex.
+func hashURL(client *http.Client, url string) (string, error) {
+	hash := sha256.New()
+	err := fetch(client, url, func(body io.Reader) error {
+		_, err := io.Copy(hash, body)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ArtifactHasher returns the SHA-256 of the artifact served at a download URL.
+type ArtifactHasher func(url string) (string, error)
+
+// downloadHasher downloads every artifact it hashes.
+func downloadHasher(client *http.Client) ArtifactHasher {
+	return func(url string) (string, error) {
+		return hashURL(client, url)
+	}
+}
+
+// installedHasher hashes the copy of an artifact proto downloaded into
+// tempDir since install started, so the bytes checked are the ones proto
+// installed. It fails rather than fetch the URL again when that copy is gone.
+func installedHasher(tempDir string, since time.Time) ArtifactHasher {
+	return func(url string) (string, error) {
+		path, ok := findDownload(tempDir, url, since)
+		if !ok {
+			return "", fmt.Errorf("proto's download of %s is not in %s", url, tempDir)
+		}
+		return hashFile(path)
+	}
+}
+
+// protoTempDir is where proto downloads artifacts before installing them.
+func protoTempDir() (string, error) {
+	if home := os.Getenv("PROTO_HOME"); home != "" {
+		return filepath.Join(home, "temp"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".proto", "temp"), nil
+}
+
+// findDownload returns the newest file below dir named after url's last
+// segment and modified at or after since.
+func findDownload(dir, url string, since time.Time) (string, bool) {
+	name := url[strings.LastIndex(url, "/")+1:]
+	var found string
+	var newest time.Time
+	_ = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || entry.Name() != name {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(since) || info.ModTime().Before(newest) {
+			return nil
+		}
+		found, newest = path, info.ModTime()
+		return nil
+	})
+	return found, found != ""
+}
+
+// hashFile returns the SHA-256 of the file at path in hex.
+func hashFile(path string) (string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", path, err)
+		}
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// downloadFile saves url to path, creating its directory, and returns the
+// SHA-256 of what was written.
+func downloadFile(client *http.Client, url, path string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", err
+	}
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", path, err)
+		}
+	}()
+
+	hash := sha256.New()
+	err = fetch(client, url, func(body io.Reader) error {
+		_, err := io.Copy(io.MultiWriter(file, hash), body)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+const maxChecksumFileSize = 1 << 20
+
+func fetchText(client *http.Client, url string) (string, error) {
+	var text []byte
+	err := fetch(client, url, func(body io.Reader) error {
+		var err error
+		text, err = io.ReadAll(io.LimitReader(body, maxChecksumFileSize))
+		return err
+	})
+	return string(text), err
+}
+
+// findChecksum looks up file in a checksum file: either "<hash>  <file>"
+// lines as written by sha256sum, or a single hash for one artifact.
+func findChecksum(content, file string) (string, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	var lines [][]string
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+
+	for _, fields := range lines {
+		if len(fields) >= 2 && isSHA256(fields[0]) && strings.TrimPrefix(strings.TrimPrefix(fields[len(fields)-1], "*"), "./") == file {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	if len(lines) == 1 && isSHA256(lines[0][0]) && (len(lines[0]) == 1 || !strings.Contains(content, " ")) {
+		return strings.ToLower(lines[0][0]), true
+	}
+	return "", false
+}
+
+func isSHA256(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// UnsupportedError reports a manifest setting that changes what gets
+// installed and that an export format cannot express.
+type UnsupportedError struct {
+	Format  string
+	Concept string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s has no equivalent for %s", e.Format, e.Concept)
+}
+
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// convertTemplate replaces a manifest template's placeholders with values
+// from vars, failing on any placeholder vars does not cover.
+func convertTemplate(format, template string, vars map[string]string) (string, error) {
+	var unsupported string
+	converted := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := vars[strings.Trim(match, "{}")]
+		if !ok && unsupported == "" {
+			unsupported = match
+		}
+		return value
+	})
+	if unsupported != "" {
+		return "", &UnsupportedError{Format: format, Concept: fmt.Sprintf("the %s placeholder in %q", unsupported, template)}
+	}
+	return converted, nil
+}
+
+// tagPrefixes returns the literal prefixes a git-tag-pattern strips from tags
+// to get versions. The default pattern strips an optional v. Patterns that
+// do more than strip a prefix are reported as unsupported.
+func tagPrefixes(format string, resolve ResolveConfig) ([]string, error) {
+	if resolve.GitTagPattern == "" {
+		return []string{"v", ""}, nil
+	}
+	unsupported := &UnsupportedError{Format: format, Concept: fmt.Sprintf("git-tag-pattern %q beyond a literal prefix", resolve.GitTagPattern)}
+
+	tree, err := syntax.Parse(resolve.GitTagPattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid git-tag-pattern: %w", err)
+	}
+	parts := tree.Sub
+	if tree.Op != syntax.OpConcat || len(parts) < 2 || parts[0].Op != syntax.OpBeginText {
+		return nil, unsupported
+	}
+	parts = parts[1:]
+	if last := parts[len(parts)-1]; last.Op == syntax.OpEndText {
+		parts = parts[:len(parts)-1]
+	}
+
+	var prefix *syntax.Regexp
+	switch len(parts) {
+	case 1:
+	case 2:
+		prefix = parts[0]
+	default:
+		return nil, unsupported
+	}
+	if capture := parts[len(parts)-1]; capture.Op != syntax.OpCapture {
+		return nil, unsupported
+	}
+
+	switch {
+	case prefix == nil:
+		return []string{""}, nil
+	case prefix.Op == syntax.OpLiteral:
+		return []string{string(prefix.Rune)}, nil
+	case prefix.Op == syntax.OpAlternate:
+		prefixes := make([]string, 0, len(prefix.Sub))
+		for _, alternative := range prefix.Sub {
+			if alternative.Op != syntax.OpLiteral {
+				return nil, unsupported
+			}
+			prefixes = append(prefixes, string(alternative.Rune))
+		}
+		return prefixes, nil
+	}
+	return nil, unsupported
+}
+
+// archiveFormat names an artifact's archive type from its extension, or raw
+// for a plain executable.
+func archiveFormat(file string) string {
+	for _, format := range []string{"tar.gz", "tgz", "tar.xz", "tar.bz2", "zip", "gz"} {
+		if strings.HasSuffix(file, "."+format) {
+			return format
+		}
+	}
+	return "raw"
+}
+
+// unpacks reports whether proto unpacks downloads, which it does unless
+// install.unpack is false.
+func (m Manifest) unpacks() bool {
+	return m.Install.Unpack == nil || *m.Install.Unpack
+}
+
+// executablePath is where the executable sits after unpacking, relative to
+// the unpacked directory.
+func (p PlatformConfig) executablePath() string {
+	path := p.ExePath
+	if path == "" {
+		path = p.BinPath
+	}
+	if path != "" && p.ArchivePrefix != "" {
+		path = p.ArchivePrefix + "/" + path
+	}
+	return path
+}
+
+// Exporter writes a manifest as another tool's definition. It returns the
+// files to write relative to the output directory and notes on settings
+// that were dropped because they do not affect what gets installed.
+type Exporter func(file ManifestFile) (files map[string]string, notes []string, err error)
+
+var exporters = map[string]Exporter{
+	"aqua": exportAquaFiles,
+	"asdf": exportAsdf,
+}
+
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	format := flags.String("format", "", "aqua for a registry.yaml, or asdf for a plugin asdf and mise can install")
+	out := flags.String("out", "", "directory to write the definitions to")
+	tools := flags.String("tools", "", "comma-separated tools to export (defaults to all)")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse export flags: %v", err)
+	}
+
+	exporter, ok := exporters[*format]
+	switch {
+	case !ok:
+		log.Fatalf("export needs -format aqua or asdf")
+	case *out == "":
+		log.Fatalf("export needs -out with the directory to write to")
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+	selected := map[string]bool{}
+	for _, tool := range strings.Split(*tools, ",") {
+		if tool = strings.TrimSpace(tool); tool != "" {
+			selected[tool] = true
+		}
+	}
+
+	failed := 0
+	for _, manifest := range workspace.Manifests {
+		if len(selected) > 0 && !selected[manifest.Stem] {
+			continue
+		}
+		files, notes, err := exporter(manifest)
+		if err != nil {
+			log.Printf("%s: %v", manifest.Stem, err)
+			failed++
+			continue
+		}
+		for _, note := range notes {
+			log.Printf("%s: %s", manifest.Stem, note)
+		}
+		for _, name := range sortedKeys(files) {
+			path := filepath.Join(*out, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+				log.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+			}
+			mode := os.FileMode(0o600)
+			if strings.Contains(name, "/bin/") {
+				mode = 0o700
+			}
+			if err := os.WriteFile(path, []byte(files[name]), mode); err != nil {
+				log.Fatalf("Failed to write %s: %v", path, err)
+			}
+		}
+		fmt.Printf("Exported %s\n", manifest.Stem)
+	}
+	if failed > 0 {
+		log.Fatalf("Failed to export %d plugin(s)", failed)
+	}
+}
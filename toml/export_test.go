@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// releaseTags returns the tags upstream could publish version 1.2.3 under
+// that both proto's git-tag-pattern and the exported package accept.
+func releaseTags(t *testing.T, manifest Manifest, pkg AquaPackage) []string {
+	t.Helper()
+	prefixes, err := tagPrefixes(aquaFormat, manifest.Resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern, err := tagPattern(manifest.Resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tags []string
+	for _, prefix := range prefixes {
+		for _, tag := range []string{prefix + "1.2.3", prefix + "v1.2.3"} {
+			if strings.HasSuffix(prefix, "v") && strings.HasPrefix(tag[len(prefix):], "v") {
+				continue
+			}
+			if _, ok := tagVersion(pattern, tag); !ok {
+				continue
+			}
+			accepted, err := pkg.accepts(tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if accepted {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if len(tags) == 0 {
+		t.Fatalf("no tag for 1.2.3 matches %q", manifest.Resolve.GitTagPattern)
+	}
+	return tags
+}
+
+func TestExportAqua(t *testing.T) {
+	manifests, err := loadManifests(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) == 0 {
+		t.Fatal("no manifests")
+	}
+
+	for _, file := range manifests {
+		t.Run(file.Stem, func(t *testing.T) {
+			files, _, err := exportAquaFiles(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, ok := files[file.Stem+"/registry.yaml"]
+			if !ok {
+				t.Fatalf("exportAquaFiles() wrote %v, want %s/registry.yaml", sortedKeys(files), file.Stem)
+			}
+			var registry AquaRegistry
+			if err := yaml.Unmarshal([]byte(content), &registry); err != nil {
+				t.Fatalf("registry.yaml does not parse: %v\n%s", err, content)
+			}
+			if len(registry.Packages) != 1 {
+				t.Fatalf("registry.yaml has %d packages, want 1", len(registry.Packages))
+			}
+			pkg := registry.Packages[0]
+
+			pattern, err := tagPattern(file.Resolve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tag := range releaseTags(t, file.Manifest, pkg) {
+				version, _ := tagVersion(pattern, tag)
+				for _, target := range releaseTargets {
+					want, supported := file.Download(target, version.String())
+					got, ok, err := pkg.Render(aquaOS[target.Platform], aquaArch[target.Arch], tag)
+					switch {
+					case err != nil:
+						t.Fatalf("%s at %s: %v\n%s", target, tag, err, content)
+					case ok != supported:
+						t.Errorf("%s at %s: aqua supports it %v, proto %v", target, tag, ok, supported)
+					case !ok:
+					case got.URL != want.URL || got.ChecksumURL != want.ChecksumURL:
+						t.Errorf("%s at %s: aqua downloads %s (checksums %q), proto %s (checksums %q)", target, tag, got.URL, got.ChecksumURL, want.URL, want.ChecksumURL)
+					}
+				}
+			}
+		})
+	}
+}
+
+var asdfRow = regexp.MustCompile(`(?m)^(\S+)\) url="([^"]*)" checksum_url="([^"]*)" bin="([^"]*)" ;;$`)
+
+func TestExportAsdf(t *testing.T) {
+	manifests, err := loadManifests(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, bashErr := exec.LookPath("bash")
+
+	for _, file := range manifests {
+		t.Run(file.Stem, func(t *testing.T) {
+			files, _, err := exportAsdf(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if bashErr == nil {
+					if output, err := exec.Command("bash", "-n", path).CombinedOutput(); err != nil {
+						t.Errorf("%s is not valid bash: %v\n%s", name, err, output)
+					}
+				}
+			}
+
+			rows := map[string][]string{}
+			for _, match := range asdfRow.FindAllStringSubmatch(files["asdf-"+file.Stem+"/lib/utils.bash"], -1) {
+				rows[match[1]] = match[2:]
+			}
+			for _, target := range releaseTargets {
+				want, supported := file.Download(target, "1.2.3")
+				row, ok := rows[target.String()]
+				switch {
+				case ok != supported:
+					t.Errorf("%s: plugin supports it %v, proto %v", target, ok, supported)
+				case !ok:
+				default:
+					url := strings.ReplaceAll(row[0], "${version}", "1.2.3")
+					checksumURL := strings.ReplaceAll(row[1], "${version}", "1.2.3")
+					if url != want.URL || checksumURL != want.ChecksumURL {
+						t.Errorf("%s: plugin downloads %s (checksums %q), proto %s (checksums %q)", target, url, checksumURL, want.URL, want.ChecksumURL)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExportAsdfListAll(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	upstream := gitRepository(t, "v1.10.0", "kubernetes-1.11.0", "v1.12.0-rc.1", "latest", "1.9.0")
+
+	script := asdfListAll("kubectl", upstream, []string{"v", "kubernetes-"})
+	output, err := exec.Command("bash", "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("list-all: %v\n%s", err, output)
+	}
+	if got, want := strings.TrimSpace(string(output)), "1.10.0 1.11.0"; got != want {
+		t.Errorf("list-all = %q, want %q", got, want)
+	}
+}
+
+func TestExportUnsupported(t *testing.T) {
+	valid := func() ManifestFile {
+		return ManifestFile{Stem: "tool", Manifest: Manifest{
+			Name:     "tool",
+			Platform: map[string]PlatformConfig{"linux": {DownloadFile: "tool-{arch}.tar.gz"}},
+			Install:  InstallConfig{DownloadURL: "https://github.com/o/tool/releases/download/v{version}/{download_file}"},
+			Resolve:  ResolveConfig{GitURL: "https://github.com/o/tool"},
+		}}
+	}
+	tests := []struct {
+		name   string
+		modify func(*ManifestFile)
+		format string
+	}{
+		{"tag pattern", func(f *ManifestFile) { f.Resolve.GitTagPattern = `^v(\d+)-stable$` }, aquaFormat},
+		{"tag pattern", func(f *ManifestFile) { f.Resolve.GitTagPattern = `^(?:v|release-)?(.*)$` }, asdfFormat},
+		{"placeholder", func(f *ManifestFile) { f.Platform["linux"] = PlatformConfig{DownloadFile: "tool-{triple}.tar.gz"} }, aquaFormat},
+		{"placeholder", func(f *ManifestFile) { f.Platform["linux"] = PlatformConfig{DownloadFile: "tool-{triple}.tar.gz"} }, asdfFormat},
+		{"platform", func(f *ManifestFile) { f.Platform["freebsd"] = PlatformConfig{DownloadFile: "tool.tar.gz"} }, aquaFormat},
+		{"platform", func(f *ManifestFile) { f.Platform["freebsd"] = PlatformConfig{DownloadFile: "tool.tar.gz"} }, asdfFormat},
+		{"git url", func(f *ManifestFile) { f.Resolve.GitURL = "https://gitlab.com/o/tool" }, aquaFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.format+" "+test.name, func(t *testing.T) {
+			file := valid()
+			if _, _, err := exporters[test.format](file); err != nil {
+				t.Fatalf("valid manifest: %v", err)
+			}
+			test.modify(&file)
+			_, _, err := exporters[test.format](file)
+			var unsupported *UnsupportedError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("export error = %v, want an UnsupportedError", err)
+			}
+			if unsupported.Format != test.format {
+				t.Errorf("UnsupportedError.Format = %q, want %q", unsupported.Format, test.format)
+			}
+		})
+	}
+}
+
+func TestExportNotes(t *testing.T) {
+	file := ManifestFile{Stem: "dprint"}
+	var err error
+	if file.Manifest, err = readManifest("dprint.toml"); err != nil {
+		t.Fatal(err)
+	}
+
+	for format, exporter := range exporters {
+		_, notes, err := exporter(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined := strings.Join(notes, "\n")
+		for _, want := range []string{"self-upgrade-commands", "{libc} on linux"} {
+			if !strings.Contains(joined, want) {
+				t.Errorf("%s notes %q, want a note on %s", format, notes, want)
+			}
+		}
+	}
+}
+
+func TestExportWithoutUnpacking(t *testing.T) {
+	file := ManifestFile{Stem: "trivy"}
+	var err error
+	if file.Manifest, err = readManifest("trivy.toml"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _, err := exportAquaFiles(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registry AquaRegistry
+	if err := yaml.Unmarshal([]byte(files["trivy/registry.yaml"]), &registry); err != nil {
+		t.Fatal(err)
+	}
+	download, _, err := registry.Packages[0].Render("linux", "amd64", "v0.67.0")
+	if err != nil || download.Format != "raw" || download.Src != "" {
+		t.Errorf("aqua renders %+v, %v, want the tar.gz installed as a raw file", download, err)
+	}
+
+	files, _, err = exportAsdf(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script := files["asdf-trivy/bin/download"]; strings.Contains(script, "tar -xzf") {
+		t.Errorf("asdf bin/download unpacks the artifact:\n%s", script)
+	}
+	if utils := files["asdf-trivy/lib/utils.bash"]; !strings.Contains(utils, `bin="trivy_${version}_Linux-64bit.tar.gz"`) {
+		t.Errorf("asdf installs a file other than the download:\n%s", utils)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/ageha734/proto-plugins/toml
 
 go 1.24.8
 
-require github.com/BurntSushi/toml v1.3.2
+require (
+	github.com/BurntSushi/toml v1.3.2
+	gopkg.in/yaml.v3 v3.0.1
+)
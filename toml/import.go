@@ -0,0 +1,538 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// protoFormat names proto in errors about aqua settings a manifest cannot
+// express.
+const protoFormat = "proto"
+
+// aquaMetadataKeys describe a package without changing what gets installed.
+var aquaMetadataKeys = map[string]bool{"description": true, "aliases": true, "search_words": true, "link": true}
+
+// yamlKeys returns the keys a struct decodes, following inline fields.
+func yamlKeys(t reflect.Type, keys map[string]bool) map[string]bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if options == "inline" {
+			yamlKeys(field.Type, keys)
+		} else if name != "" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// selectAquaPackage reads a registry.yaml and returns the package selector
+// names: its name, owner/repo or repository name. An empty selector picks
+// the only package. The notes list settings of the package that are not
+// imported.
+func selectAquaPackage(content []byte, selector string) (AquaPackage, []string, error) {
+	var registry AquaRegistry
+	if err := yaml.Unmarshal(content, &registry); err != nil {
+		return AquaPackage{}, nil, err
+	}
+	var raw struct {
+		Packages []map[string]yaml.Node `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return AquaPackage{}, nil, err
+	}
+
+	var matches []int
+	for i, pkg := range registry.Packages {
+		repository := pkg.RepoOwner + "/" + pkg.RepoName
+		if selector == "" || selector == pkg.Name || selector == repository || selector == pkg.RepoName {
+			matches = append(matches, i)
+		}
+	}
+	switch {
+	case len(matches) == 0 && selector == "":
+		return AquaPackage{}, nil, errors.New("registry has no packages")
+	case len(matches) == 0:
+		return AquaPackage{}, nil, fmt.Errorf("registry has no package %s", selector)
+	case len(matches) > 1 && selector == "":
+		return AquaPackage{}, nil, fmt.Errorf("registry has %d packages; pass -package", len(matches))
+	case len(matches) > 1:
+		return AquaPackage{}, nil, fmt.Errorf("registry has %d packages matching %s", len(matches), selector)
+	}
+
+	var notes []string
+	known := yamlKeys(reflect.TypeOf(AquaPackage{}), map[string]bool{})
+	for _, key := range sortedKeys(raw.Packages[matches[0]]) {
+		if !known[key] && !aquaMetadataKeys[key] {
+			notes = append(notes, fmt.Sprintf("aqua's %s is not imported", key))
+		}
+	}
+	return registry.Packages[matches[0]], notes, nil
+}
+
+var (
+	semverConstraint = regexp.MustCompile(`^semver\("([^"]+)"\)$`)
+	versionEquals    = regexp.MustCompile(`^Version == "([^"]+)"$`)
+	semverBound      = regexp.MustCompile(`^(<=|>=|<|>|=|!=)?\s*v?(\d+(?:\.\d+)*)$`)
+)
+
+// aquaConstraint evaluates a version_constraint for a release. It knows the
+// forms registries use to split a package's history: true, false, an exact
+// Version and semver() bounds.
+func aquaConstraint(expression, tag string, version Version) (bool, error) {
+	expression = strings.TrimSpace(expression)
+	switch expression {
+	case "", "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if match := versionEquals.FindStringSubmatch(expression); match != nil {
+		return tag == match[1], nil
+	}
+	match := semverConstraint.FindStringSubmatch(expression)
+	if match == nil {
+		return false, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("version_constraint %q", expression)}
+	}
+	for _, bound := range strings.Split(match[1], ",") {
+		parts := semverBound.FindStringSubmatch(strings.TrimSpace(bound))
+		if parts == nil {
+			return false, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("version_constraint %q", expression)}
+		}
+		limit, _ := parseVersion(parts[2])
+		compared := version.Compare(limit)
+		ok := map[string]bool{
+			"<=": compared <= 0, ">=": compared >= 0, "<": compared < 0, ">": compared > 0,
+			"": compared == 0, "=": compared == 0, "!=": compared != 0,
+		}[parts[1]]
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// definition returns the settings aqua uses for a release: the package's
+// own when its version_constraint matches, otherwise the package with the
+// first matching version_overrides entry merged in.
+func (p AquaPackage) definition(tag string, version Version) (AquaPackage, []string, error) {
+	var notes []string
+	if len(p.VersionOverrides) > 0 {
+		notes = append(notes, fmt.Sprintf("version_overrides describe other releases; the manifest follows the definition for %s", tag))
+	}
+	ok, err := aquaConstraint(p.VersionConstraint, tag, version)
+	if err != nil || ok {
+		return p, notes, err
+	}
+	for _, override := range p.VersionOverrides {
+		ok, err := aquaConstraint(override.VersionConstraint, tag, version)
+		if err != nil {
+			return AquaPackage{}, nil, err
+		}
+		if ok {
+			return p.merge(override), notes, nil
+		}
+	}
+	return AquaPackage{}, nil, fmt.Errorf("no version_constraint matches %s", tag)
+}
+
+// merge returns the package with the settings a version override sets.
+func (p AquaPackage) merge(override AquaPackage) AquaPackage {
+	merged := p
+	merged.VersionConstraint, merged.VersionOverrides = "", nil
+	if override.Type != "" {
+		merged.Type = override.Type
+	}
+	if override.Asset != "" {
+		merged.Asset = override.Asset
+	}
+	if override.URL != "" {
+		merged.URL = override.URL
+	}
+	if override.Format != "" {
+		merged.Format = override.Format
+	}
+	if override.Replacements != nil {
+		merged.Replacements = override.Replacements
+	}
+	if override.Checksum != nil {
+		merged.Checksum = override.Checksum
+	}
+	if override.Files != nil {
+		merged.Files = override.Files
+	}
+	if override.FormatOverrides != nil {
+		merged.FormatOverrides = override.FormatOverrides
+	}
+	if override.SupportedEnvs != nil {
+		merged.SupportedEnvs = override.SupportedEnvs
+	}
+	if override.Overrides != nil {
+		merged.Overrides = override.Overrides
+	}
+	merged.Rosetta2 = merged.Rosetta2 || override.Rosetta2
+	merged.WindowsArmEmu = merged.WindowsArmEmu || override.WindowsArmEmu
+	return merged
+}
+
+// aquaTagPattern is the git-tag-pattern that keeps the tags the package's
+// version_prefix or version_filter keeps.
+func aquaTagPattern(p AquaPackage) (string, error) {
+	switch {
+	case p.VersionPrefix != "" && p.VersionFilter != "":
+		return "", &UnsupportedError{Format: protoFormat, Concept: "version_prefix together with version_filter"}
+	case p.VersionPrefix != "":
+		return fmt.Sprintf("^(?:%s)(.*)$", regexp.QuoteMeta(p.VersionPrefix)), nil
+	case p.VersionFilter == "":
+		return "", nil
+	}
+	prefixes, negated, err := parseVersionFilter(p.VersionFilter)
+	if err != nil || negated {
+		return "", protoFilterError(p, err)
+	}
+	quoted := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		quoted = append(quoted, regexp.QuoteMeta(prefix))
+	}
+	return fmt.Sprintf("^(?:%s)(.*)$", strings.Join(quoted, "|")), nil
+}
+
+// protoFilterError reports a version_filter that no git-tag-pattern can
+// express as missing from proto.
+func protoFilterError(p AquaPackage, err error) error {
+	if errors.Is(err, errVersionFilter) {
+		return &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("version_filter %q", p.VersionFilter)}
+	}
+	return err
+}
+
+// commonDir returns the longest prefix of values that ends in a slash.
+func commonDir(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix[:strings.LastIndex(prefix, "/")+1]
+}
+
+// importedDownload is what a platform downloads, with the version and the
+// architecture replaced by placeholders.
+type importedDownload struct {
+	URL         string
+	ChecksumURL string
+	Src         string
+	Format      string
+}
+
+// importAqua translates an aqua package into a manifest for name, using tag,
+// one of the package's releases, to render its templates. It returns the
+// release's version and notes on anything a reviewer should check by hand.
+// The manifest is only returned when it downloads the same URLs as the
+// package on every target.
+func importAqua(name string, pkg AquaPackage, tag string) (Manifest, Version, []string, error) {
+	if pkg.RepoOwner == "" || pkg.RepoName == "" {
+		return Manifest{}, nil, nil, errors.New("package has no repo_owner and repo_name to resolve versions from")
+	}
+	resolve := ResolveConfig{GitURL: fmt.Sprintf("https://github.com/%s/%s", pkg.RepoOwner, pkg.RepoName)}
+	var err error
+	if resolve.GitTagPattern, err = aquaTagPattern(pkg); err != nil {
+		return Manifest{}, nil, nil, err
+	}
+	pattern, err := tagPattern(resolve)
+	if err != nil {
+		return Manifest{}, nil, nil, err
+	}
+	version, ok := tagVersion(pattern, tag)
+	if accepted, err := pkg.accepts(tag); err != nil {
+		return Manifest{}, nil, nil, protoFilterError(pkg, err)
+	} else if !ok || !accepted {
+		return Manifest{}, nil, nil, fmt.Errorf("tag %s is not a release of the package", tag)
+	}
+	value := version.String()
+	if !strings.Contains(tag, value) {
+		return Manifest{}, nil, nil, fmt.Errorf("tag %s does not spell its version as %s", tag, value)
+	}
+
+	pkg, notes, err := pkg.definition(tag, version)
+	if err != nil {
+		return Manifest{}, nil, nil, err
+	}
+	if pkg.Rosetta2 {
+		return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: "rosetta2, which installs the amd64 asset on arm64 macOS"}
+	}
+	if pkg.WindowsArmEmu {
+		notes = append(notes, "windows_arm_emulation is dropped: the manifest does not install on arm64 Windows")
+	}
+
+	generalize := func(text, arch string) string {
+		text = strings.ReplaceAll(text, value, "{version}")
+		if arch != "" {
+			text = strings.ReplaceAll(text, arch, "{arch}")
+		}
+		return text
+	}
+
+	manifest := Manifest{Name: name, Type: "cli", Platform: map[string]PlatformConfig{}, Resolve: resolve}
+	downloads := map[string]importedDownload{}
+	archNames := map[string]string{}
+	for _, platform := range platformOrder {
+		goos := aquaOS[platform]
+		rendered := map[string]AquaDownload{}
+		for _, arch := range []string{"x86_64", "aarch64"} {
+			download, ok, err := pkg.Render(goos, aquaArch[arch], tag)
+			if err != nil {
+				return Manifest{}, nil, nil, fmt.Errorf("%s/%s: %w", platform, arch, err)
+			}
+			if ok {
+				rendered[arch] = download
+			}
+			if checksum := pkg.resolve(goos, aquaArch[arch]).Checksum; ok && checksum != nil && (checksum.Enabled == nil || *checksum.Enabled) {
+				if checksum.Algorithm != "" && checksum.Algorithm != "sha256" {
+					return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("%s checksums", checksum.Algorithm)}
+				}
+				if checksum.FileFormat == "regexp" {
+					return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: "checksum files read with a regexp"}
+				}
+			}
+		}
+		switch len(rendered) {
+		case 0:
+			notes = append(notes, fmt.Sprintf("%s: the package does not support it", platform))
+			continue
+		case 1:
+			return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("installing on only one %s architecture", platform)}
+		}
+
+		// A platform whose architectures share a download, such as a
+		// universal macOS binary, does not need the architecture.
+		shared := rendered["x86_64"].URL == rendered["aarch64"].URL
+		var first *importedDownload
+		for _, arch := range []string{"x86_64", "aarch64"} {
+			download := rendered[arch]
+			archName := ""
+			if !shared {
+				archName = aquaArch[arch]
+				if replacement, ok := pkg.resolve(goos, aquaArch[arch]).Replacements[archName]; ok {
+					archName = replacement
+				}
+				if existing, ok := archNames[arch]; ok && existing != archName {
+					return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("naming %s %q on %s and %q elsewhere", arch, archName, platform, existing)}
+				}
+				archNames[arch] = archName
+			}
+			current := importedDownload{
+				URL:         generalize(download.URL, archName),
+				ChecksumURL: generalize(download.ChecksumURL, archName),
+				Src:         generalize(download.Src, archName),
+				Format:      download.Format,
+			}
+			if first == nil {
+				first = &current
+			} else if current != *first {
+				return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("%s downloads that differ by more than the architecture", platform)}
+			}
+		}
+		downloads[platform] = *first
+	}
+	if len(downloads) == 0 {
+		return Manifest{}, nil, nil, errors.New("package supports none of linux, darwin and windows")
+	}
+
+	var urls, checksumURLs []string
+	for _, platform := range sortedKeys(downloads) {
+		urls = append(urls, downloads[platform].URL)
+		if downloads[platform].ChecksumURL != "" {
+			checksumURLs = append(checksumURLs, downloads[platform].ChecksumURL)
+		}
+	}
+	downloadDir := commonDir(urls)
+	manifest.Install.DownloadURL = downloadDir + "{download_file}"
+	var checksumDir string
+	if len(checksumURLs) > 0 {
+		checksumDir = commonDir(checksumURLs)
+		manifest.Install.ChecksumURL = checksumDir + "{checksum_file}"
+	}
+
+	raw := 0
+	for platform, download := range downloads {
+		config := PlatformConfig{DownloadFile: strings.TrimPrefix(download.URL, downloadDir)}
+		if download.ChecksumURL != "" {
+			config.ChecksumFile = strings.TrimPrefix(download.ChecksumURL, checksumDir)
+		} else {
+			notes = append(notes, fmt.Sprintf("%s: no checksum; downloads are not verified", platform))
+		}
+
+		format := download.Format
+		if format == "" {
+			format = archiveFormat(config.DownloadFile)
+		}
+		switch extension := archiveFormat(config.DownloadFile); {
+		case format == "raw":
+			raw++
+		case format != extension:
+			return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("format %s for %s, which proto unpacks by its extension", format, config.DownloadFile)}
+		default:
+			config.BinPath = download.Src
+			if config.BinPath == "" {
+				config.BinPath = name
+			}
+			if platform == "windows" && !strings.HasSuffix(config.BinPath, ".exe") {
+				config.BinPath += ".exe"
+			}
+		}
+		manifest.Platform[platform] = config
+	}
+	switch {
+	case raw == len(downloads):
+		unpack := false
+		manifest.Install.Unpack = &unpack
+	case raw > 0:
+		for platform, config := range manifest.Platform {
+			if config.BinPath == "" && archiveFormat(config.DownloadFile) != "raw" {
+				return Manifest{}, nil, nil, &UnsupportedError{Format: protoFormat, Concept: fmt.Sprintf("installing the %s archive without unpacking it", platform)}
+			}
+		}
+	}
+
+	for arch, archName := range archNames {
+		if archName != arch {
+			if manifest.Install.Arch == nil {
+				manifest.Install.Arch = map[string]string{}
+			}
+			manifest.Install.Arch[arch] = archName
+		}
+	}
+
+	if err := compareDownloads(manifest, pkg, tag, value); err != nil {
+		return Manifest{}, nil, nil, fmt.Errorf("imported manifest downloads differ from the package: %w", err)
+	}
+	// Matching at tag does not show {version} replaced the right text, so
+	// compare at a release tagged with the same prefix too.
+	synthetic := strings.Replace(tag, value, syntheticVersion, 1)
+	if err := compareDownloads(manifest, pkg, synthetic, syntheticVersion); err != nil {
+		return Manifest{}, nil, nil, fmt.Errorf("imported manifest downloads differ from the package at %s: %w", synthetic, err)
+	}
+	return manifest, version, notes, nil
+}
+
+// syntheticVersion stands in for a release other than the one imported.
+const syntheticVersion = "9.8.7"
+
+// compareDownloads checks that manifest at version and pkg at tag support
+// the same targets and download the same artifacts and checksum files.
+func compareDownloads(manifest Manifest, pkg AquaPackage, tag, version string) error {
+	var problems []error
+	for _, target := range releaseTargets {
+		want, supported, err := pkg.Render(aquaOS[target.Platform], aquaArch[target.Arch], tag)
+		if err != nil {
+			return err
+		}
+		got, ok := manifest.Download(target, version)
+		switch {
+		case ok != supported:
+			problems = append(problems, fmt.Errorf("%s: aqua supports it %t, proto %t", target, supported, ok))
+		case ok && got.URL != want.URL:
+			problems = append(problems, fmt.Errorf("%s: aqua downloads %s, proto %s", target, want.URL, got.URL))
+		case ok && got.ChecksumURL != want.ChecksumURL:
+			problems = append(problems, fmt.Errorf("%s: aqua reads checksums from %q, proto from %q", target, want.ChecksumURL, got.ChecksumURL))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// latestAquaTag returns the tag of the newest stable release the package
+// accepts.
+func latestAquaTag(lister TagLister, pkg AquaPackage) (string, error) {
+	source, err := aquaTagPattern(pkg)
+	if err != nil {
+		return "", err
+	}
+	resolve := ResolveConfig{GitURL: fmt.Sprintf("https://github.com/%s/%s", pkg.RepoOwner, pkg.RepoName), GitTagPattern: source}
+	pattern, err := tagPattern(resolve)
+	if err != nil {
+		return "", err
+	}
+	tags, err := lister.Tags(resolve.GitURL)
+	if err != nil {
+		return "", err
+	}
+
+	var newest Version
+	var newestTag string
+	for _, tag := range tags {
+		version, ok := tagVersion(pattern, tag)
+		if accepted, err := pkg.accepts(tag); err != nil {
+			return "", protoFilterError(pkg, err)
+		} else if ok && accepted && (newest == nil || version.Compare(newest) > 0) {
+			newest, newestTag = version, tag
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("%s has no stable release tag; pass -tag", resolve.GitURL)
+	}
+	return newestTag, nil
+}
+
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	registry := flags.String("registry", "", "aqua registry.yaml with the package definition")
+	selector := flags.String("package", "", "package to import when the registry has several: its name, owner/repo or repository name")
+	name := flags.String("name", "", "tool name, used for the manifest, test and .prototools entries (defaults to the repository name)")
+	tag := flags.String("tag", "", "release tag to render the package with, such as v1.2.3 (defaults to the newest release)")
+	command := flags.String("command", "", "command the test runs after installing (defaults to \"<name> --version\")")
+	force := flags.Bool("force", false, "replace an existing manifest and test spec")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse import flags: %v", err)
+	}
+	if *registry == "" {
+		log.Fatalf("import needs -registry with the aqua registry.yaml")
+	}
+
+	content, err := os.ReadFile(filepath.Clean(*registry))
+	if err != nil {
+		log.Fatalf("Failed to read registry: %v", err)
+	}
+	pkg, notes, err := selectAquaPackage(content, *selector)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *registry, err)
+	}
+	if *name == "" {
+		*name = pkg.RepoName
+	}
+	if *tag == "" {
+		if *tag, err = latestAquaTag(gitTagLister{}, pkg); err != nil {
+			log.Fatalf("Failed to find the newest release: %v", err)
+		}
+	}
+	if *command == "" {
+		*command = *name + " --version"
+	}
+
+	manifest, version, importNotes, err := importAqua(*name, pkg, *tag)
+	if err != nil {
+		log.Fatalf("Failed to import %s: %v", *name, err)
+	}
+
+	written, err := writeScaffold(workspaceRoot(*root), Scaffold{Manifest: manifest, Command: *command, Version: version.String()}, *force)
+	if err != nil {
+		log.Fatalf("Failed to write scaffold: %v", err)
+	}
+	for _, path := range written {
+		fmt.Printf("wrote %s\n", path)
+	}
+	for _, note := range append(notes, importNotes...) {
+		fmt.Printf("check: %s\n", note)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestImportAquaRoundTrip(t *testing.T) {
+	manifests, err := loadManifests(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range manifests {
+		t.Run(file.Stem, func(t *testing.T) {
+			files, _, err := exportAquaFiles(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content := files[file.Stem+"/registry.yaml"]
+			pkg, notes, err := selectAquaPackage([]byte(content), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(notes) > 0 {
+				t.Errorf("selectAquaPackage() notes = %q, want none for an exported package", notes)
+			}
+
+			for _, tag := range releaseTags(t, file.Manifest, pkg) {
+				imported, version, _, err := importAqua(file.Stem, pkg, tag)
+				if err != nil {
+					t.Fatalf("importAqua(%s): %v\n%s", tag, err, content)
+				}
+				for _, release := range []string{version.String(), syntheticVersion} {
+					for _, target := range releaseTargets {
+						want, supported := file.Download(target, release)
+						got, ok := imported.Download(target, release)
+						if ok != supported || got.URL != want.URL || got.ChecksumURL != want.ChecksumURL {
+							t.Errorf("%s at %s (imported from %s): imported manifest downloads %+v (%v), original %+v (%v)", target, release, tag, got, ok, want, supported)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// aquaRegistry follows the layout of packages in aqua's standard registry.
+const aquaRegistry = `packages:
+  - type: github_release
+    repo_owner: example
+    repo_name: widget
+    description: Widgets from the command line
+    version_constraint: "false"
+    version_overrides:
+      - version_constraint: semver("< 1.0.0")
+        asset: widget-{{.OS}}-{{.Arch}}.tar.gz
+        checksum:
+          enabled: false
+      - version_constraint: "true"
+        asset: widget_{{trimV .Version}}_{{title .OS}}_{{.Arch}}.{{.Format}}
+        format: tar.gz
+        format_overrides:
+          - goos: windows
+            format: zip
+        replacements:
+          amd64: x86_64
+          darwin: macOS
+        checksum:
+          type: github_release
+          asset: widget_{{trimV .Version}}_checksums.txt
+          algorithm: sha256
+        files:
+          - name: widget
+            src: "{{.AssetWithoutExt}}/widget"
+    complete_windows_ext: false
+  - type: http
+    repo_owner: example
+    repo_name: gadget
+    url: https://downloads.example.com/gadget/{{.Version}}/gadget-{{.OS}}-{{.Arch}}
+    format: raw
+    supported_envs:
+      - linux
+      - darwin
+`
+
+func TestImportAqua(t *testing.T) {
+	if _, _, err := selectAquaPackage([]byte(aquaRegistry), ""); err == nil {
+		t.Error("selectAquaPackage() picked one of several packages without a selector")
+	}
+	pkg, notes, err := selectAquaPackage([]byte(aquaRegistry), "example/widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"aqua's complete_windows_ext is not imported"}; strings.Join(notes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("selectAquaPackage() notes = %q, want %q", notes, want)
+	}
+
+	manifest, version, notes, err := importAqua("widget", pkg, "v1.4.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.String() != "1.4.0" {
+		t.Errorf("importAqua() version = %s, want 1.4.0", version)
+	}
+	if !strings.Contains(strings.Join(notes, "\n"), "the definition for v1.4.0") {
+		t.Errorf("importAqua() notes = %q, want one on version_overrides", notes)
+	}
+	want := `name = "widget"
+type = "cli"
+
+[platform.linux]
+download-file = "widget_{version}_Linux_{arch}.tar.gz"
+checksum-file = "widget_{version}_checksums.txt"
+bin-path = "widget_{version}_Linux_{arch}/widget"
+
+[platform.macos]
+download-file = "widget_{version}_MacOS_{arch}.tar.gz"
+checksum-file = "widget_{version}_checksums.txt"
+bin-path = "widget_{version}_MacOS_{arch}/widget"
+
+[platform.windows]
+download-file = "widget_{version}_Windows_{arch}.zip"
+checksum-file = "widget_{version}_checksums.txt"
+bin-path = "widget_{version}_Windows_{arch}/widget.exe"
+
+[install]
+download-url = "https://github.com/example/widget/releases/download/v{version}/{download_file}"
+checksum-url = "https://github.com/example/widget/releases/download/v{version}/{checksum_file}"
+
+[install.arch]
+aarch64 = "arm64"
+
+[resolve]
+git-url = "https://github.com/example/widget"
+`
+	if got := renderManifest(manifest); got != want {
+		t.Errorf("renderManifest() =\n%s\nwant\n%s", got, want)
+	}
+
+	manifest, _, _, err = importAqua("widget", pkg, "v0.9.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.Platform["linux"]; got.DownloadFile != "widget-linux-{arch}.tar.gz" || got.ChecksumFile != "" {
+		t.Errorf("importAqua() before 1.0.0 linux = %+v, want the old asset without checksums", got)
+	}
+
+	pkg, _, err = selectAquaPackage([]byte(aquaRegistry), "gadget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, _, notes, err = importAqua("gadget", pkg, "v2.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `name = "gadget"
+type = "cli"
+
+[platform.linux]
+download-file = "gadget-linux-{arch}"
+
+[platform.macos]
+download-file = "gadget-darwin-{arch}"
+
+[install]
+download-url = "https://downloads.example.com/gadget/v{version}/{download_file}"
+unpack = false
+
+[install.arch]
+aarch64 = "arm64"
+x86_64 = "amd64"
+
+[resolve]
+git-url = "https://github.com/example/gadget"
+`
+	if got := renderManifest(manifest); got != want {
+		t.Errorf("renderManifest() =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(strings.Join(notes, "\n"), "windows: the package does not support it") {
+		t.Errorf("importAqua() notes = %q, want one on windows", notes)
+	}
+
+	// The pinned 1.4.0 looks like the version at v1.4.0 but stays put at
+	// other releases.
+	var pinned AquaPackage
+	if err := yaml.Unmarshal([]byte("type: github_release\nrepo_owner: example\nrepo_name: widget\nasset: widget-1.4.0-{{.OS}}-{{.Arch}}.tar.gz\n"), &pinned); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := importAqua("widget", pinned, "v1.4.0"); err == nil || !strings.Contains(err.Error(), "at v9.8.7") {
+		t.Errorf("importAqua() with a pinned asset error = %v, want a mismatch at v9.8.7", err)
+	}
+}
+
+func TestImportAquaUnsupported(t *testing.T) {
+	const base = `type: github_release
+repo_owner: example
+repo_name: widget
+asset: widget-{{.OS}}-{{.Arch}}.tar.gz
+`
+	tests := map[string]string{
+		"package type":       "type: go_install\nrepo_owner: example\nrepo_name: widget\n",
+		"rosetta2":           base + "rosetta2: true\n",
+		"checksum algorithm": base + "checksum:\n  type: github_release\n  asset: sums.txt\n  algorithm: sha512\n",
+		"version filter":     base + "version_filter: not (Version contains \"-rc\")\n",
+		"one architecture":   base + "supported_envs: [linux, darwin/amd64]\n",
+		"per-arch asset":     base + "overrides:\n  - goos: linux\n    goarch: arm64\n    asset: widget-linux-aarch64-musl.tar.gz\n",
+		"constraint":         base + "version_constraint: Version startsWith \"v1\"\n",
+		"extension":          base + "format: zip\n",
+	}
+	for name, definition := range tests {
+		t.Run(name, func(t *testing.T) {
+			var pkg AquaPackage
+			if err := yaml.Unmarshal([]byte(definition), &pkg); err != nil {
+				t.Fatal(err)
+			}
+			_, _, _, err := importAqua("widget", pkg, "v1.4.0")
+			var unsupported *UnsupportedError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("importAqua() error = %v, want an UnsupportedError", err)
+			}
+			if unsupported.Format != protoFormat {
+				t.Errorf("UnsupportedError.Format = %q, want %q", unsupported.Format, protoFormat)
+			}
+		})
+	}
+}
+
+func TestVersionFilterError(t *testing.T) {
+	pkg := AquaPackage{VersionFilter: `Version contains "-rc"`}
+	_, err := pkg.accepts("v1.4.0")
+	var unsupported *UnsupportedError
+	if !errors.Is(err, errVersionFilter) || errors.As(err, &unsupported) {
+		t.Errorf("accepts() error = %v, want errVersionFilter without a format", err)
+	}
+}
+
+func TestAquaConstraint(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+		{`Version == "v1.4.0"`, true},
+		{`Version == "v1.3.0"`, false},
+		{`semver("< 1.0.0")`, false},
+		{`semver(">= 1.0.0, < 2")`, true},
+		{`semver("<= 1.4")`, true},
+		{`semver("> v1.4.0")`, false},
+	}
+	for _, tt := range tests {
+		got, err := aquaConstraint(tt.expression, "v1.4.0", Version{1, 4, 0})
+		if err != nil {
+			t.Errorf("aquaConstraint(%q) error = %v", tt.expression, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("aquaConstraint(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+// tagMap lists tags without git, by repository URL.
+type tagMap map[string][]string
+
+func (m tagMap) Tags(gitURL string) ([]string, error) {
+	return m[gitURL], nil
+}
+
+func TestLatestAquaTag(t *testing.T) {
+	lister := tagMap{"https://github.com/example/widget": {"v1.9.0", "v1.10.0", "v1.11.0-rc.1", "widget-2.0.0"}}
+	tag, err := latestAquaTag(lister, AquaPackage{RepoOwner: "example", RepoName: "widget"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "v1.10.0" {
+		t.Errorf("latestAquaTag() = %s, want v1.10.0", tag)
+	}
+
+	tag, err = latestAquaTag(lister, AquaPackage{RepoOwner: "example", RepoName: "widget", VersionPrefix: "widget-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "widget-2.0.0" {
+		t.Errorf("latestAquaTag() with version_prefix = %s, want widget-2.0.0", tag)
+	}
+}
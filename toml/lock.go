@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// lockFileName is written to the workspace root next to .prototools.
+const lockFileName = "proto-plugins.lock"
+
+const lockHeader = "# Generated by `go -C toml run . lock`. Do not edit by hand.\n\n"
+
+// Lockfile pins every plugin to one version and the SHA-256 of the artifact
+// each supported target downloads for it.
+type Lockfile struct {
+	Plugins map[string]LockedPlugin `toml:"plugins"`
+}
+
+// LockedPlugin is the version a constraint resolved to and its artifacts.
+type LockedPlugin struct {
+	Version   string           `toml:"version"`
+	Artifacts []LockedArtifact `toml:"artifacts"`
+}
+
+// LockedArtifact is one target's rendered download URL and checksum.
+type LockedArtifact struct {
+	Platform string `toml:"platform"`
+	Arch     string `toml:"arch"`
+	URL      string `toml:"url"`
+	SHA256   string `toml:"sha256"`
+}
+
+// Artifact returns the locked artifact for target.
+func (p LockedPlugin) Artifact(target Target) (LockedArtifact, bool) {
+	for _, artifact := range p.Artifacts {
+		if artifact.Platform == target.Platform && artifact.Arch == target.Arch {
+			return artifact, true
+		}
+	}
+	return LockedArtifact{}, false
+}
+
+func lockPath(root string) string {
+	return filepath.Join(root, lockFileName)
+}
+
+// readLockfile returns an empty lockfile when path does not exist.
+func readLockfile(path string) (*Lockfile, error) {
+	lock := &Lockfile{Plugins: map[string]LockedPlugin{}}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return lock, nil
+	}
+	if _, err := toml.DecodeFile(filepath.Clean(path), lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if lock.Plugins == nil {
+		lock.Plugins = map[string]LockedPlugin{}
+	}
+	return lock, nil
+}
+
+// Write renders the lock with plugins sorted by name, one table per artifact.
+func (l *Lockfile) Write(path string) error {
+	var out strings.Builder
+	out.WriteString(lockHeader)
+	for i, name := range sortedKeys(l.Plugins) {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		plugin := l.Plugins[name]
+		fmt.Fprintf(&out, "[plugins.%s]\nversion = %s\n", name, tomlString(plugin.Version))
+		for _, artifact := range plugin.Artifacts {
+			fmt.Fprintf(&out, "\n[[plugins.%s.artifacts]]\n", name)
+			fmt.Fprintf(&out, "platform = %s\narch = %s\n", tomlString(artifact.Platform), tomlString(artifact.Arch))
+			fmt.Fprintf(&out, "url = %s\nsha256 = %s\n", tomlString(artifact.URL), tomlString(artifact.SHA256))
+		}
+	}
+	return os.WriteFile(filepath.Clean(path), []byte(out.String()), 0o600)
+}
+
+// satisfies reports whether version meets a .prototools constraint: >= is a
+// lower bound, ^ keeps the first non-zero segment, ~ keeps major.minor, and a
+// bare or = version matches every release it prefixes.
+func satisfies(version Version, constraint string) bool {
+	operator, value := splitConstraint(constraint)
+	if value == "" || value == "latest" || value == "*" {
+		return true
+	}
+	bound, ok := parseVersion(value)
+	if !ok {
+		return false
+	}
+
+	samePrefix := func(segments int) bool {
+		for i := 0; i < segments && i < len(bound); i++ {
+			if i >= len(version) || version[i] != bound[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch operator {
+	case ">=":
+		return version.Compare(bound) >= 0
+	case "^":
+		segments := 1
+		for segments < len(bound) && bound[segments-1] == 0 {
+			segments++
+		}
+		return version.Compare(bound) >= 0 && samePrefix(segments)
+	case "~":
+		return version.Compare(bound) >= 0 && samePrefix(2)
+	default:
+		return samePrefix(len(bound))
+	}
+}
+
+// resolveVersion picks the newest stable release that satisfies constraint,
+// the version proto installs for it.
+func resolveVersion(manifest Manifest, constraint string, lister TagLister) (Version, error) {
+	if manifest.Resolve.GitURL == "" {
+		return nil, fmt.Errorf("no [resolve] git-url")
+	}
+	tags, err := lister.Tags(manifest.Resolve.GitURL)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := stableVersions(manifest.Resolve, tags)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if satisfies(versions[i], constraint) {
+			return versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no release of %s satisfies %q", manifest.Resolve.GitURL, constraint)
+}
+
+// Locker computes artifact checksums, preferring the upstream checksum file,
+// then vendored checksums, and hashing the download when neither has the
+// artifact.
+type Locker struct {
+	Client    *http.Client
+	Lister    TagLister
+	checksums map[string]string
+}
+
+func (l *Locker) checksumFile(url string) (string, error) {
+	if content, ok := l.checksums[url]; ok {
+		return content, nil
+	}
+	content, err := fetchText(l.Client, url)
+	if err != nil {
+		return "", err
+	}
+	if l.checksums == nil {
+		l.checksums = map[string]string{}
+	}
+	l.checksums[url] = content
+	return content, nil
+}
+
+func (l *Locker) checksum(download Download, version string, vendored VendoredChecksums) (string, error) {
+	if download.ChecksumURL != "" {
+		content, err := l.checksumFile(download.ChecksumURL)
+		if err != nil {
+			return "", err
+		}
+		if sum, ok := findChecksum(content, download.File); ok {
+			return sum, nil
+		}
+	}
+	if sum, ok := vendored.Lookup(version, download.Target); ok {
+		return sum, nil
+	}
+	return hashURL(l.Client, download.URL)
+}
+
+// Lock resolves a plugin's constraint and records every supported target's
+// artifact.
+func (l *Locker) Lock(manifest Manifest, constraint string, vendored VendoredChecksums) (LockedPlugin, error) {
+	version, err := resolveVersion(manifest, constraint, l.Lister)
+	if err != nil {
+		return LockedPlugin{}, err
+	}
+
+	locked := LockedPlugin{Version: version.String()}
+	for _, target := range releaseTargets {
+		download, ok := manifest.Download(target, locked.Version)
+		if !ok {
+			continue
+		}
+		sum, err := l.checksum(download, locked.Version, vendored)
+		if err != nil {
+			return LockedPlugin{}, fmt.Errorf("%s: %w", target, err)
+		}
+		locked.Artifacts = append(locked.Artifacts, LockedArtifact{
+			Platform: target.Platform,
+			Arch:     target.Arch,
+			URL:      download.URL,
+			SHA256:   sum,
+		})
+	}
+	return locked, nil
+}
+
+// currentTarget is the target of the running machine, named the way
+// manifests name platforms and proto names architectures.
+func currentTarget() Target {
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	}
+	return Target{Platform: getPlatform(), Arch: arch}
+}
+
+// verifyArtifact hashes the artifact manifest renders for target at the
+// locked version and fails when its URL or SHA-256 differ from the lock.
+func verifyArtifact(hash ArtifactHasher, manifest Manifest, locked LockedPlugin, target Target) error {
+	artifact, ok := locked.Artifact(target)
+	if !ok {
+		return fmt.Errorf("%s is not locked for %s", lockFileName, target)
+	}
+	download, ok := manifest.Download(target, locked.Version)
+	if !ok {
+		return fmt.Errorf("manifest does not support %s", target.Platform)
+	}
+	if download.URL != artifact.URL {
+		return fmt.Errorf("manifest downloads %s but %s has %s; regenerate the lock", download.URL, lockFileName, artifact.URL)
+	}
+
+	return verifyDownload(hash, download.URL, artifact.SHA256, lockFileName)
+}
+
+func runLock(args []string) {
+	flags := flag.NewFlagSet("lock", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	tools := flags.String("tools", "", "comma-separated tools to relock (defaults to all)")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse lock flags: %v", err)
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+	path := lockPath(workspace.Root)
+	lock, err := readLockfile(path)
+	if err != nil {
+		log.Fatalf("Failed to read lockfile: %v", err)
+	}
+
+	selected := map[string]bool{}
+	for _, tool := range strings.Split(*tools, ",") {
+		if tool = strings.TrimSpace(tool); tool != "" {
+			selected[tool] = true
+		}
+	}
+
+	locker := &Locker{Client: defaultHTTPClient(), Lister: gitTagLister{}}
+	failed := 0
+	for _, manifest := range workspace.Manifests {
+		if len(selected) > 0 && !selected[manifest.Stem] {
+			continue
+		}
+		vendored, err := readVendoredChecksums(vendoredChecksumPath(filepath.Dir(manifest.Path), manifest.Stem))
+		if err != nil {
+			log.Printf("%s: %v", manifest.Stem, err)
+			failed++
+			continue
+		}
+		locked, err := locker.Lock(manifest.Manifest, workspace.Prototools.Tools[manifest.Stem], vendored)
+		if err != nil {
+			log.Printf("%s: %v", manifest.Stem, err)
+			failed++
+			continue
+		}
+		lock.Plugins[manifest.Stem] = locked
+		fmt.Printf("%s %s (%d artifacts)\n", manifest.Stem, locked.Version, len(locked.Artifacts))
+	}
+
+	if err := lock.Write(path); err != nil {
+		log.Fatalf("Failed to write lockfile: %v", err)
+	}
+	if failed > 0 {
+		log.Fatalf("Failed to lock %d plugin(s)", failed)
+	}
+}
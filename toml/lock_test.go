@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestDownload(t *testing.T) {
+	manifest, err := readManifest("dprint.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	download, ok := manifest.Download(Target{"linux", "aarch64"}, "0.50.2")
+	if !ok {
+		t.Fatal("Download() does not support linux")
+	}
+	want := Download{
+		Target:       Target{"linux", "aarch64"},
+		File:         "dprint-aarch64-unknown-linux-gnu.zip",
+		URL:          "https://github.com/dprint/dprint/releases/download/0.50.2/dprint-aarch64-unknown-linux-gnu.zip",
+		ChecksumFile: "SHASUMS256.txt",
+		ChecksumURL:  "https://github.com/dprint/dprint/releases/download/0.50.2/SHASUMS256.txt",
+	}
+	if download != want {
+		t.Errorf("Download() = %+v, want %+v", download, want)
+	}
+
+	if _, ok := (Manifest{}).Download(Target{"linux", "x86_64"}, "1.0.0"); ok {
+		t.Error("Download() supports a platform the manifest lacks")
+	}
+}
+
+func TestFindChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	other := strings.Repeat("cd", sha256.Size)
+
+	for _, tt := range []struct {
+		content string
+		want    string
+		ok      bool
+	}{
+		{other + "  tool_linux.tar.gz\n" + sum + "  tool_darwin.tar.gz\n", sum, true},
+		{sum + " *./tool_darwin.tar.gz\n", sum, true},
+		{strings.ToUpper(sum) + "\n", sum, true},
+		{other + "  tool_linux.tar.gz\n", "", false},
+		{"not a checksum\n", "", false},
+	} {
+		got, ok := findChecksum(tt.content, "tool_darwin.tar.gz")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("findChecksum(%q) = %q, %t, want %q, %t", tt.content, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	for _, tt := range []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.3.0", ">=1.2.0", true},
+		{"1.1.9", ">=1.2.0", false},
+		{"1.9.0", "^1.2.0", true},
+		{"2.0.0", "^1.2.0", false},
+		{"0.2.5", "^0.2.1", true},
+		{"0.3.0", "^0.2.1", false},
+		{"1.2.9", "~1.2.0", true},
+		{"1.3.0", "~1.2.0", false},
+		{"1.2.7", "1.2", true},
+		{"1.2.7", "=1.2.6", false},
+		{"3.0.0", "latest", true},
+	} {
+		version, _ := parseVersion(tt.version)
+		if got := satisfies(version, tt.constraint); got != tt.want {
+			t.Errorf("satisfies(%s, %q) = %t, want %t", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// lockManifest points at a release server: linux artifacts are listed in a
+// checksum file, macOS ones are not and have to be hashed.
+func lockManifest(server, gitURL string) string {
+	return `name = "tool"
+
+[platform.linux]
+download-file = "tool_{version}_linux_{arch}.tar.gz"
+checksum-file = "checksums.txt"
+
+[platform.macos]
+download-file = "tool_{version}_darwin_{arch}.tar.gz"
+
+[install]
+download-url = "` + server + `/v{version}/{download_file}"
+checksum-url = "` + server + `/v{version}/{checksum_file}"
+
+[install.arch]
+x86_64 = "amd64"
+aarch64 = "arm64"
+
+[resolve]
+git-url = ` + tomlString(gitURL) + `
+`
+}
+
+func TestLock(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	artifacts := map[string]string{
+		"/v1.2.3/tool_1.2.3_linux_amd64.tar.gz":  "linux amd64",
+		"/v1.2.3/tool_1.2.3_linux_arm64.tar.gz":  "linux arm64",
+		"/v1.2.3/tool_1.2.3_darwin_amd64.tar.gz": "darwin amd64",
+		"/v1.2.3/tool_1.2.3_darwin_arm64.tar.gz": "darwin arm64",
+	}
+	artifacts["/v1.2.3/checksums.txt"] = sha256Hex("linux amd64") + "  tool_1.2.3_linux_amd64.tar.gz\n" +
+		sha256Hex("linux arm64") + "  tool_1.2.3_linux_arm64.tar.gz\n"
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		content, ok := artifacts[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"tool.toml": lockManifest(server.URL, gitRepository(t, "v1.2.0", "v1.2.3", "v1.3.0"))})
+	manifest, err := readManifest(filepath.Join(root, "tool.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker := &Locker{Client: server.Client(), Lister: gitTagLister{}}
+	locked, err := locker.Lock(manifest, "~1.2.0", nil)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	want := LockedPlugin{Version: "1.2.3", Artifacts: []LockedArtifact{
+		{"linux", "x86_64", server.URL + "/v1.2.3/tool_1.2.3_linux_amd64.tar.gz", sha256Hex("linux amd64")},
+		{"linux", "aarch64", server.URL + "/v1.2.3/tool_1.2.3_linux_arm64.tar.gz", sha256Hex("linux arm64")},
+		{"macos", "x86_64", server.URL + "/v1.2.3/tool_1.2.3_darwin_amd64.tar.gz", sha256Hex("darwin amd64")},
+		{"macos", "aarch64", server.URL + "/v1.2.3/tool_1.2.3_darwin_arm64.tar.gz", sha256Hex("darwin arm64")},
+	}}
+	if !reflect.DeepEqual(locked, want) {
+		t.Errorf("Lock() = %+v, want %+v", locked, want)
+	}
+	if requests["/v1.2.3/checksums.txt"] != 1 || requests["/v1.2.3/tool_1.2.3_linux_amd64.tar.gz"] != 0 {
+		t.Errorf("requests = %v, want the checksum file fetched once and no linux download", requests)
+	}
+
+	path := lockPath(root)
+	if err := (&Lockfile{Plugins: map[string]LockedPlugin{"tool": locked}}).Write(path); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := readLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lock.Plugins["tool"], want) {
+		t.Errorf("readLockfile() = %+v, want %+v", lock.Plugins["tool"], want)
+	}
+
+	target := Target{"macos", "aarch64"}
+	if err := verifyArtifact(downloadHasher(server.Client()), manifest, locked, target); err != nil {
+		t.Errorf("verifyArtifact() error = %v", err)
+	}
+
+	artifacts["/v1.2.3/tool_1.2.3_darwin_arm64.tar.gz"] = "tampered"
+	if err := verifyArtifact(downloadHasher(server.Client()), manifest, locked, target); err == nil || !strings.Contains(err.Error(), "expects "+sha256Hex("darwin arm64")) {
+		t.Errorf("verifyArtifact() with a changed artifact error = %v", err)
+	}
+
+	manifest.Install.Arch["aarch64"] = "aarch64"
+	if err := verifyArtifact(downloadHasher(server.Client()), manifest, locked, target); err == nil || !strings.Contains(err.Error(), "regenerate the lock") {
+		t.Errorf("verifyArtifact() with a changed manifest error = %v", err)
+	}
+
+	if err := verifyArtifact(downloadHasher(server.Client()), manifest, locked, Target{"windows", "x86_64"}); err == nil {
+		t.Error("verifyArtifact() accepted a target missing from the lock")
+	}
+}
+
+func TestReadLockfileMissing(t *testing.T) {
+	lock, err := readLockfile(lockPath(t.TempDir()))
+	if err != nil || len(lock.Plugins) != 0 {
+		t.Errorf("readLockfile() = %+v, %v, want an empty lock", lock, err)
+	}
+}
+
+func TestInstalledHasher(t *testing.T) {
+	url := "https://example.com/v1.2.3/tool_1.2.3_linux_amd64.tar.gz"
+	tempDir := t.TempDir()
+	writeFiles(t, tempDir, map[string]string{"tool/1.2.3/tool_1.2.3_linux_amd64.tar.gz": "installed"})
+
+	if sum, err := installedHasher(tempDir, time.Now().Add(-time.Minute))(url); err != nil || sum != sha256Hex("installed") {
+		t.Errorf("installedHasher() = %s, %v, want the hash of proto's download", sum, err)
+	}
+	if _, err := installedHasher(tempDir, time.Now().Add(time.Minute))(url); err == nil {
+		t.Error("installedHasher() accepted a download from an earlier install")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const usage = `Usage: go run . <command> [flags]
+
+Commands:
+  sync       check that manifests, their test specs and .prototools agree
+  scaffold   add a manifest, test spec and .prototools entries for a new tool
+  catalog    regenerate the plugin catalog in README.md (-check to verify it)
+  outdated   compare .prototools constraints with upstream releases (-write to bump them)
+  lock       pin each plugin's version and artifact checksums in proto-plugins.lock
+  checksums  vendor artifact checksums for a tool whose upstream publishes none
+  mirror     copy releases, manifests and a .prototools overlay into an air-gapped mirror
+  export     write manifests as aqua registry or asdf/mise plugin definitions
+  import     add a plugin and its test from an aqua registry package
+`
+
+// main runs the manifest tooling. The plugin tests in this package do not
+// need it; they run through go test.
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "sync":
+		runSync(os.Args[2:])
+	case "scaffold":
+		runScaffold(os.Args[2:])
+	case "catalog":
+		runCatalog(os.Args[2:])
+	case "outdated":
+		runOutdated(os.Args[2:])
+	case "lock":
+		runLock(os.Args[2:])
+	case "checksums":
+		runChecksums(os.Args[2:])
+	case "mirror":
+		runMirror(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	case "import":
+		runImport(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		log.Fatalf("Unknown command %q", os.Args[1])
+	}
+}
+
+// workspaceRoot returns root, or when it is empty the nearest directory
+// upwards from the current one that contains .prototools.
+func workspaceRoot(root string) string {
+	if root != "" {
+		return root
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get current directory: %v", err)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".prototools")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			log.Fatalf("No .prototools found above the current directory; pass -root")
+		}
+		dir = parent
+	}
+}
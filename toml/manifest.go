@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Manifest is a proto TOML plugin as written in this directory.
+type Manifest struct {
+	Name     string                    `toml:"name"`
+	Type     string                    `toml:"type"`
+	Metadata ManifestMetadata          `toml:"metadata"`
+	Platform map[string]PlatformConfig `toml:"platform"`
+	Install  InstallConfig             `toml:"install"`
+	Resolve  ResolveConfig             `toml:"resolve"`
+}
+
+type ManifestMetadata struct {
+	SelfUpgradeCommands []string `toml:"self-upgrade-commands"`
+}
+
+type PlatformConfig struct {
+	DownloadFile  string `toml:"download-file"`
+	ChecksumFile  string `toml:"checksum-file"`
+	BinPath       string `toml:"bin-path"`
+	ExePath       string `toml:"exe-path"`
+	ArchivePrefix string `toml:"archive-prefix"`
+}
+
+type InstallConfig struct {
+	DownloadURL string            `toml:"download-url"`
+	ChecksumURL string            `toml:"checksum-url"`
+	Unpack      *bool             `toml:"unpack"`
+	Arch        map[string]string `toml:"arch"`
+}
+
+type ResolveConfig struct {
+	GitURL        string `toml:"git-url"`
+	GitTagPattern string `toml:"git-tag-pattern"`
+}
+
+// ManifestFile is a manifest together with where it was read from. Stem is
+// the file name without .toml, which proto uses as the plugin id.
+type ManifestFile struct {
+	Path string
+	Stem string
+	Manifest
+}
+
+func readManifest(path string) (Manifest, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var manifest Manifest
+	if err := toml.Unmarshal(content, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// loadManifests reads every *.toml file in dir, sorted by file name.
+func loadManifests(dir string) ([]ManifestFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	manifests := make([]ManifestFile, 0, len(paths))
+	for _, path := range paths {
+		manifest, err := readManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, ManifestFile{
+			Path:     path,
+			Stem:     strings.TrimSuffix(filepath.Base(path), ".toml"),
+			Manifest: manifest,
+		})
+	}
+	return manifests, nil
+}
+
+// platformNames returns the platforms a manifest supports, sorted.
+func (m Manifest) platformNames() []string {
+	names := make([]string, 0, len(m.Platform))
+	for name := range m.Platform {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// platformOrder is the order platforms appear in manifests.
+var platformOrder = []string{"linux", "macos", "windows"}
+
+func (m Manifest) orderedPlatforms() []string {
+	names := make([]string, 0, len(m.Platform))
+	for _, name := range platformOrder {
+		if _, ok := m.Platform[name]; ok {
+			names = append(names, name)
+		}
+	}
+	for _, name := range m.platformNames() {
+		if !contains(platformOrder, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// renderManifest writes a manifest in the layout used by the files in this
+// directory, leaving out empty settings.
+func renderManifest(m Manifest) string {
+	var b strings.Builder
+	line := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s = %s\n", key, tomlString(value))
+		}
+	}
+
+	line("name", m.Name)
+	line("type", m.Type)
+
+	if len(m.Metadata.SelfUpgradeCommands) > 0 {
+		quoted := make([]string, 0, len(m.Metadata.SelfUpgradeCommands))
+		for _, command := range m.Metadata.SelfUpgradeCommands {
+			quoted = append(quoted, tomlString(command))
+		}
+		fmt.Fprintf(&b, "\n[metadata]\nself-upgrade-commands = [%s]\n", strings.Join(quoted, ", "))
+	}
+
+	for _, name := range m.orderedPlatforms() {
+		platform := m.Platform[name]
+		fmt.Fprintf(&b, "\n[platform.%s]\n", name)
+		line("download-file", platform.DownloadFile)
+		line("checksum-file", platform.ChecksumFile)
+		line("archive-prefix", platform.ArchivePrefix)
+		line("exe-path", platform.ExePath)
+		line("bin-path", platform.BinPath)
+	}
+
+	b.WriteString("\n[install]\n")
+	line("download-url", m.Install.DownloadURL)
+	line("checksum-url", m.Install.ChecksumURL)
+	if m.Install.Unpack != nil {
+		fmt.Fprintf(&b, "unpack = %t\n", *m.Install.Unpack)
+	}
+
+	if len(m.Install.Arch) > 0 {
+		b.WriteString("\n[install.arch]\n")
+		keys := make([]string, 0, len(m.Install.Arch))
+		for key := range m.Install.Arch {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			line(key, m.Install.Arch[key])
+		}
+	}
+
+	b.WriteString("\n[resolve]\n")
+	line("git-url", m.Resolve.GitURL)
+	line("git-tag-pattern", m.Resolve.GitTagPattern)
+
+	return b.String()
+}
+
+// tomlString quotes value as a TOML basic string.
+func tomlString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
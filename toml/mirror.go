@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Mirror layout below the output directory, served as-is by any static HTTP
+// server at BaseURL:
+//
+//	<name>/<version>/<download file>   artifacts, as mirrorURL addresses them
+//	<name>/<version>/<checksum file>   upstream checksum files
+//	<name>.git                         bare repository with the release tags
+//	manifests/<name>.toml              manifests pointing at the above
+//	.prototools                        overlay pinning the mirrored versions
+const mirrorManifestDir = "manifests"
+
+// MirrorSelection is a plugin to mirror and, optionally, the version to
+// mirror instead of the newest one satisfying its constraint.
+type MirrorSelection struct {
+	Tool    string
+	Version string
+}
+
+// parseMirrorSelections reads a comma-separated list of tool or tool@version.
+func parseMirrorSelections(value string) []MirrorSelection {
+	var selections []MirrorSelection
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		tool, version, _ := strings.Cut(item, "@")
+		selections = append(selections, MirrorSelection{Tool: tool, Version: version})
+	}
+	return selections
+}
+
+// Mirrorer copies plugin releases into an air-gapped mirror.
+type Mirrorer struct {
+	Client  *http.Client
+	Lister  TagLister
+	Dir     string
+	BaseURL string
+}
+
+// MirroredRelease is one plugin version copied into the mirror.
+type MirroredRelease struct {
+	Tool      string
+	Version   Version
+	Tag       string
+	Artifacts int
+}
+
+// releaseTag resolves the version to mirror and the tag it was released
+// under, so the mirror's repository resolves it the way upstream does.
+func (m *Mirrorer) releaseTag(manifest Manifest, constraint, requested string) (Version, string, error) {
+	if manifest.Resolve.GitURL == "" {
+		return nil, "", fmt.Errorf("no [resolve] git-url")
+	}
+	tags, err := m.Lister.Tags(manifest.Resolve.GitURL)
+	if err != nil {
+		return nil, "", err
+	}
+	pattern, err := tagPattern(manifest.Resolve)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var found Version
+	var foundTag string
+	for _, tag := range tags {
+		version, ok := tagVersion(pattern, tag)
+		if !ok {
+			continue
+		}
+		if requested != "" {
+			if version.String() == requested {
+				return version, tag, nil
+			}
+			continue
+		}
+		if satisfies(version, constraint) && (found == nil || version.Compare(found) > 0) {
+			found, foundTag = version, tag
+		}
+	}
+	switch {
+	case requested != "":
+		return nil, "", fmt.Errorf("no tag of %s is release %s", manifest.Resolve.GitURL, requested)
+	case found == nil:
+		return nil, "", fmt.Errorf("no release of %s satisfies %q", manifest.Resolve.GitURL, constraint)
+	}
+	return found, foundTag, nil
+}
+
+// Mirror downloads a release's artifacts and checksum files for every
+// supported target, verifies artifacts listed in a checksum file or in the
+// vendored checksums, and tags the release in the mirror's repository.
+func (m *Mirrorer) Mirror(file ManifestFile, constraint, requested string, vendored VendoredChecksums) (MirroredRelease, error) {
+	version, tag, err := m.releaseTag(file.Manifest, constraint, requested)
+	if err != nil {
+		return MirroredRelease{}, err
+	}
+	release := MirroredRelease{Tool: file.Stem, Version: version, Tag: tag}
+	dir := filepath.Join(m.Dir, file.Stem, version.String())
+
+	checksumFiles := map[string]string{}
+	for _, target := range releaseTargets {
+		download, ok := file.Download(target, version.String())
+		if !ok {
+			continue
+		}
+
+		if download.ChecksumURL != "" {
+			if _, ok := checksumFiles[download.ChecksumFile]; !ok {
+				path := filepath.Join(dir, filepath.FromSlash(download.ChecksumFile))
+				if _, err := downloadFile(m.Client, download.ChecksumURL, path); err != nil {
+					return MirroredRelease{}, fmt.Errorf("%s: %w", target, err)
+				}
+				content, err := os.ReadFile(filepath.Clean(path))
+				if err != nil {
+					return MirroredRelease{}, err
+				}
+				checksumFiles[download.ChecksumFile] = string(content)
+			}
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(download.File))
+		sum, err := downloadFile(m.Client, download.URL, path)
+		if err != nil {
+			return MirroredRelease{}, fmt.Errorf("%s: %w", target, err)
+		}
+		want, ok := findChecksum(checksumFiles[download.ChecksumFile], download.File)
+		if !ok {
+			want, ok = vendored.Lookup(version.String(), target)
+		}
+		if ok && sum != want {
+			if err := os.Remove(path); err != nil {
+				log.Printf("Failed to remove %s: %v", path, err)
+			}
+			return MirroredRelease{}, fmt.Errorf("%s: %s has SHA-256 %s, expected %s", target, download.URL, sum, want)
+		}
+		release.Artifacts++
+	}
+	if release.Artifacts == 0 {
+		return MirroredRelease{}, fmt.Errorf("manifest supports none of the mirrored targets")
+	}
+
+	if err := m.tagRelease(file.Stem, tag); err != nil {
+		return MirroredRelease{}, err
+	}
+	if err := m.writeManifest(file); err != nil {
+		return MirroredRelease{}, err
+	}
+	return release, nil
+}
+
+// tagRelease adds tag to the mirror's bare repository and refreshes the
+// files git needs to list tags over plain HTTP.
+func (m *Mirrorer) tagRelease(tool, tag string) error {
+	repository := filepath.Join(m.Dir, tool+".git")
+	if _, err := git("", "init", "--quiet", "--bare", repository); err != nil {
+		return err
+	}
+	tree, err := git(repository, "mktree")
+	if err != nil {
+		return err
+	}
+	commit, err := git(repository, "-c", "user.name=mirror", "-c", "user.email=mirror@localhost", "commit-tree", tree, "-m", "Release "+tag)
+	if err != nil {
+		return err
+	}
+	if _, err := git(repository, "tag", "--force", tag, commit); err != nil {
+		return err
+	}
+	_, err = git(repository, "update-server-info")
+	return err
+}
+
+func git(dir string, args ...string) (string, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader("")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) {
+			return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// mirrorManifest rewrites a manifest's download, checksum and git URLs to
+// the mirror.
+func mirrorManifest(file ManifestFile, baseURL string) Manifest {
+	base := strings.TrimRight(baseURL, "/")
+	manifest := file.Manifest
+	manifest.Install.DownloadURL = mirrorURL(base, file.Stem, "{version}", "{download_file}")
+	if manifest.Install.ChecksumURL != "" {
+		manifest.Install.ChecksumURL = mirrorURL(base, file.Stem, "{version}", "{checksum_file}")
+	}
+	manifest.Resolve.GitURL = base + "/" + file.Stem + ".git"
+	return manifest
+}
+
+func (m *Mirrorer) writeManifest(file ManifestFile) error {
+	path := filepath.Join(m.Dir, mirrorManifestDir, file.Stem+".toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(renderManifest(mirrorManifest(file, m.BaseURL))), 0o600)
+}
+
+// renderMirrorOverlay pins each mirrored tool to its newest mirrored version
+// and points its plugin at the mirror's manifest.
+func renderMirrorOverlay(baseURL string, releases []MirroredRelease) string {
+	newest := map[string]Version{}
+	for _, release := range releases {
+		if current, ok := newest[release.Tool]; !ok || release.Version.Compare(current) > 0 {
+			newest[release.Tool] = release.Version
+		}
+	}
+	tools := sortedKeys(newest)
+
+	var out strings.Builder
+	out.WriteString("# Overlay for the air-gapped mirror, generated by `go -C toml run . mirror`.\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&out, "%s = %s\n", tool, tomlString(newest[tool].String()))
+	}
+	out.WriteString("\n[plugins]\n")
+	for _, tool := range tools {
+		fmt.Fprintf(&out, "%s = %s\n", tool, tomlString(strings.TrimRight(baseURL, "/")+"/"+mirrorManifestDir+"/"+tool+".toml"))
+	}
+	return out.String()
+}
+
+func runMirror(args []string) {
+	flags := flag.NewFlagSet("mirror", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	out := flags.String("out", "", "directory to build the mirror in")
+	baseURL := flags.String("base-url", "", "URL the mirror directory will be served from")
+	tools := flags.String("tools", "", "comma-separated tool or tool@version to mirror (defaults to every plugin at its constraint)")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse mirror flags: %v", err)
+	}
+	switch {
+	case *out == "":
+		log.Fatalf("mirror needs -out with the directory to build the mirror in")
+	case *baseURL == "":
+		log.Fatalf("mirror needs -base-url with the URL the mirror will be served from")
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+	manifests := map[string]ManifestFile{}
+	for _, manifest := range workspace.Manifests {
+		manifests[manifest.Stem] = manifest
+	}
+
+	selections := parseMirrorSelections(*tools)
+	if len(selections) == 0 {
+		for _, manifest := range workspace.Manifests {
+			selections = append(selections, MirrorSelection{Tool: manifest.Stem})
+		}
+	}
+	sort.SliceStable(selections, func(i, j int) bool { return selections[i].Tool < selections[j].Tool })
+
+	mirrorer := &Mirrorer{Client: defaultHTTPClient(), Lister: gitTagLister{}, Dir: *out, BaseURL: *baseURL}
+	var releases []MirroredRelease
+	failed := 0
+	for _, selection := range selections {
+		manifest, ok := manifests[selection.Tool]
+		if !ok {
+			log.Printf("%s: no manifest toml/%s.toml", selection.Tool, selection.Tool)
+			failed++
+			continue
+		}
+		vendored, err := readVendoredChecksums(vendoredChecksumPath(filepath.Dir(manifest.Path), manifest.Stem))
+		if err != nil {
+			log.Printf("%s: %v", selection.Tool, err)
+			failed++
+			continue
+		}
+
+		release, err := mirrorer.Mirror(manifest, workspace.Prototools.Tools[selection.Tool], selection.Version, vendored)
+		if err != nil {
+			log.Printf("%s: %v", selection.Tool, err)
+			failed++
+			continue
+		}
+		releases = append(releases, release)
+		fmt.Printf("%s %s (%d artifacts)\n", release.Tool, release.Version, release.Artifacts)
+	}
+
+	if len(releases) > 0 {
+		overlay := filepath.Join(*out, ".prototools")
+		if err := os.WriteFile(overlay, []byte(renderMirrorOverlay(*baseURL, releases)), 0o600); err != nil {
+			log.Fatalf("Failed to write overlay: %v", err)
+		}
+		fmt.Printf("Wrote %s\n", overlay)
+	}
+	if failed > 0 {
+		log.Fatalf("Failed to mirror %d plugin(s)", failed)
+	}
+}
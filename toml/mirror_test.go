@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMirrorSelections(t *testing.T) {
+	got := parseMirrorSelections(" helm, trivy@0.67.2,,kubectl@1.34.1")
+	want := []MirrorSelection{{"helm", ""}, {"trivy", "0.67.2"}, {"kubectl", "1.34.1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMirrorSelections() = %v, want %v", got, want)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	files := map[string]string{}
+	for _, version := range []string{"1.2.3", "1.3.0"} {
+		for _, file := range []string{"linux_amd64", "linux_arm64", "darwin_amd64", "darwin_arm64"} {
+			files["/v"+version+"/tool_"+version+"_"+file+".tar.gz"] = version + " " + file
+		}
+		files["/v"+version+"/checksums.txt"] = sha256Hex(version+" linux_amd64") + "  tool_" + version + "_linux_amd64.tar.gz\n" +
+			sha256Hex(version+" linux_arm64") + "  tool_" + version + "_linux_arm64.tar.gz\n"
+	}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer upstream.Close()
+
+	out := t.TempDir()
+	mirror := httptest.NewServer(http.FileServer(http.Dir(out)))
+	defer mirror.Close()
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"tool.toml": lockManifest(upstream.URL, gitRepository(t, "v1.2.0", "v1.2.3", "v1.3.0", "v1.4.0-rc.1"))})
+	manifest, err := readManifest(filepath.Join(root, "tool.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := ManifestFile{Path: filepath.Join(root, "tool.toml"), Stem: "tool", Manifest: manifest}
+
+	mirrorer := &Mirrorer{Client: upstream.Client(), Lister: gitTagLister{}, Dir: out, BaseURL: mirror.URL + "/"}
+	vendored := VendoredChecksums{"1.2.3": {"macos/aarch64": sha256Hex("1.2.3 darwin_arm64")}}
+	first, err := mirrorer.Mirror(file, "~1.2.0", "", vendored)
+	if err != nil {
+		t.Fatalf("Mirror() error = %v", err)
+	}
+	if want := (MirroredRelease{Tool: "tool", Version: Version{1, 2, 3}, Tag: "v1.2.3", Artifacts: 4}); !reflect.DeepEqual(first, want) {
+		t.Errorf("Mirror() = %+v, want %+v", first, want)
+	}
+	second, err := mirrorer.Mirror(file, "~1.2.0", "1.3.0", nil)
+	if err != nil {
+		t.Fatalf("Mirror() of a requested version error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(out, "tool", "1.2.3", "checksums.txt"))
+	if err != nil || string(content) != files["/v1.2.3/checksums.txt"] {
+		t.Errorf("mirrored checksum file = %q, %v", content, err)
+	}
+
+	// The mirrored manifest resolves and downloads everything from the mirror.
+	mirrored, err := readManifest(filepath.Join(out, mirrorManifestDir, "tool.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mirrored.Resolve.GitURL != mirror.URL+"/tool.git" {
+		t.Errorf("mirrored git-url = %q", mirrored.Resolve.GitURL)
+	}
+	version, err := resolveVersion(mirrored, ">=1.0.0", gitTagLister{})
+	if err != nil || version.String() != "1.3.0" {
+		t.Errorf("resolveVersion() from the mirror = %v, %v, want 1.3.0", version, err)
+	}
+
+	download, _ := mirrored.Download(Target{"linux", "aarch64"}, "1.2.3")
+	if download.URL != mirror.URL+"/tool/1.2.3/tool_1.2.3_linux_arm64.tar.gz" || download.ChecksumURL != mirror.URL+"/tool/1.2.3/checksums.txt" {
+		t.Errorf("mirrored download = %+v", download)
+	}
+	locked, err := (&Locker{Client: mirror.Client(), Lister: gitTagLister{}}).Lock(mirrored, "~1.2.0", nil)
+	if err != nil {
+		t.Fatalf("Lock() from the mirror error = %v", err)
+	}
+	for _, artifact := range locked.Artifacts {
+		if err := verifyArtifact(downloadHasher(mirror.Client()), mirrored, locked, Target{artifact.Platform, artifact.Arch}); err != nil {
+			t.Errorf("verifyArtifact() from the mirror error = %v", err)
+		}
+	}
+
+	wantOverlay := `# Overlay for the air-gapped mirror, generated by ` + "`go -C toml run . mirror`" + `.
+tool = "1.3.0"
+
+[plugins]
+tool = "` + mirror.URL + `/manifests/tool.toml"
+`
+	if got := renderMirrorOverlay(mirror.URL+"/", []MirroredRelease{first, second}); got != wantOverlay {
+		t.Errorf("renderMirrorOverlay() =\n%s\nwant\n%s", got, wantOverlay)
+	}
+
+	files["/v1.2.3/tool_1.2.3_linux_amd64.tar.gz"] = "tampered"
+	if _, err := mirrorer.Mirror(file, "~1.2.0", "", nil); err == nil || !strings.Contains(err.Error(), "expected "+sha256Hex("1.2.3 linux_amd64")) {
+		t.Errorf("Mirror() of a tampered artifact error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "tool", "1.2.3", "tool_1.2.3_linux_amd64.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("tampered artifact was left in the mirror: %v", err)
+	}
+	if _, err := mirrorer.Mirror(file, "~1.2.0", "9.9.9", nil); err == nil || !strings.Contains(err.Error(), "is release 9.9.9") {
+		t.Errorf("Mirror() of a missing version error = %v", err)
+	}
+}
+
+func TestMirrorManifest(t *testing.T) {
+	manifest, err := readManifest("helm.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mirrored := mirrorManifest(ManifestFile{Stem: "helm", Manifest: manifest}, "https://mirror.internal/proto/")
+	if mirrored.Install.DownloadURL != "https://mirror.internal/proto/helm/{version}/{download_file}" ||
+		mirrored.Install.ChecksumURL != "https://mirror.internal/proto/helm/{version}/{checksum_file}" ||
+		mirrored.Resolve.GitURL != "https://mirror.internal/proto/helm.git" {
+		t.Errorf("mirrorManifest() install = %+v, resolve = %+v", mirrored.Install, mirrored.Resolve)
+	}
+	if !reflect.DeepEqual(mirrored.Platform, manifest.Platform) || mirrored.Resolve.GitTagPattern != manifest.Resolve.GitTagPattern {
+		t.Error("mirrorManifest() changed more than the URLs")
+	}
+	if manifest.Resolve.GitURL == mirrored.Resolve.GitURL {
+		t.Error("mirrorManifest() modified the original manifest")
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultTagPattern mirrors proto's default: an optional v before the
+// version.
+const defaultTagPattern = `^v?(\d+(?:\.\d+)*(?:-[0-9A-Za-z.]+)?)$`
+
+// Version is a stable release number such as 1.34.1.
+type Version []int
+
+func parseVersion(value string) (Version, bool) {
+	parts := strings.Split(value, ".")
+	version := make(Version, 0, len(parts))
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, false
+		}
+		version = append(version, number)
+	}
+	return version, len(version) > 0
+}
+
+// Compare orders versions segment by segment; missing segments count as 0.
+func (v Version) Compare(other Version) int {
+	for i := 0; i < max(len(v), len(other)); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	parts := make([]string, 0, len(v))
+	for _, number := range v {
+		parts = append(parts, strconv.Itoa(number))
+	}
+	return strings.Join(parts, ".")
+}
+
+// TagLister lists the tags of a git repository.
+type TagLister interface {
+	Tags(gitURL string) ([]string, error)
+}
+
+// gitTagLister asks the remote with git ls-remote, the same source proto
+// resolves versions from.
+type gitTagLister struct{}
+
+func (gitTagLister) Tags(gitURL string) ([]string, error) {
+	output, err := exec.Command("git", "ls-remote", "--tags", "--refs", gitURL).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote %s: %w", gitURL, err)
+	}
+
+	var tags []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		_, ref, ok := strings.Cut(scanner.Text(), "\t")
+		if tag, isTag := strings.CutPrefix(ref, "refs/tags/"); ok && isTag {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, scanner.Err()
+}
+
+// stableVersions extracts release versions from tags with the manifest's
+// git-tag-pattern, dropping pre-releases, sorted oldest first.
+func stableVersions(resolve ResolveConfig, tags []string) ([]Version, error) {
+	pattern, err := tagPattern(resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for _, tag := range tags {
+		if version, ok := tagVersion(pattern, tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+	return versions, nil
+}
+
+func tagPattern(resolve ResolveConfig) (*regexp.Regexp, error) {
+	source := resolve.GitTagPattern
+	if source == "" {
+		source = defaultTagPattern
+	}
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid git-tag-pattern: %w", err)
+	}
+	return pattern, nil
+}
+
+// tagVersion returns the stable release a tag names, taken from the
+// pattern's first capture group when it has one. A leading v in the capture
+// is dropped, as proto does for patterns such as ^kustomize/(.*)$.
+func tagVersion(pattern *regexp.Regexp, tag string) (Version, bool) {
+	match := pattern.FindStringSubmatch(tag)
+	if match == nil {
+		return nil, false
+	}
+	value := match[0]
+	if len(match) > 1 {
+		value = match[1]
+	}
+	return parseVersion(strings.TrimPrefix(value, "v"))
+}
+
+// constraintOperators are the prefixes kept when a constraint is bumped.
+var constraintOperators = []string{">=", "^", "~", "="}
+
+func splitConstraint(constraint string) (operator, version string) {
+	for _, operator := range constraintOperators {
+		if rest, ok := strings.CutPrefix(constraint, operator); ok {
+			return operator, strings.TrimSpace(rest)
+		}
+	}
+	return "", strings.TrimSpace(constraint)
+}
+
+// Update compares one tool's constraint with its upstream releases.
+type Update struct {
+	Tool       string
+	Constraint string
+	Latest     Version
+	Behind     int
+	Err        error
+}
+
+// Outdated reports whether a newer release than the constraint exists.
+func (u Update) Outdated() bool {
+	return u.Err == nil && u.Behind > 0
+}
+
+// Bumped returns the constraint moved to the latest release, keeping its
+// operator.
+func (u Update) Bumped() string {
+	operator, _ := splitConstraint(u.Constraint)
+	return operator + u.Latest.String()
+}
+
+// Distance names the largest version segment that changed.
+func (u Update) Distance() string {
+	_, current := splitConstraint(u.Constraint)
+	version, ok := parseVersion(current)
+	if !ok || !u.Outdated() {
+		return "-"
+	}
+	releases := fmt.Sprintf("%d releases", u.Behind)
+	if u.Behind == 1 {
+		releases = "1 release"
+	}
+	for i, name := range []string{"major", "minor", "patch"} {
+		if i >= len(version) || i >= len(u.Latest) || version[i] != u.Latest[i] {
+			return name + ", " + releases
+		}
+	}
+	return releases
+}
+
+func checkUpdates(workspace *Workspace, lister TagLister) []Update {
+	updates := make([]Update, 0, len(workspace.Manifests))
+	for _, manifest := range workspace.Manifests {
+		update := Update{Tool: manifest.Stem, Constraint: workspace.Prototools.Tools[manifest.Stem]}
+		update.Latest, update.Behind, update.Err = latestRelease(manifest.Manifest, update.Constraint, lister)
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func latestRelease(manifest Manifest, constraint string, lister TagLister) (Version, int, error) {
+	if manifest.Resolve.GitURL == "" {
+		return nil, 0, fmt.Errorf("no [resolve] git-url")
+	}
+	tags, err := lister.Tags(manifest.Resolve.GitURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	versions, err := stableVersions(manifest.Resolve, tags)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(versions) == 0 {
+		return nil, 0, fmt.Errorf("no tag of %s matches the tag pattern", manifest.Resolve.GitURL)
+	}
+
+	latest := versions[len(versions)-1]
+	_, current := splitConstraint(constraint)
+	version, ok := parseVersion(current)
+	if !ok {
+		return latest, 0, nil
+	}
+
+	behind := 0
+	for _, candidate := range versions {
+		if candidate.Compare(version) > 0 {
+			behind++
+		}
+	}
+	return latest, behind, nil
+}
+
+func runOutdated(args []string) {
+	flags := flag.NewFlagSet("outdated", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	write := flags.Bool("write", false, "move outdated constraints in .prototools to the latest release")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse outdated flags: %v", err)
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+
+	updates := checkUpdates(workspace, gitTagLister{})
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TOOL\tCONSTRAINT\tLATEST\tBEHIND")
+	outdated := 0
+	for _, update := range updates {
+		switch {
+		case update.Err != nil:
+			fmt.Fprintf(table, "%s\t%s\t-\terror: %v\n", update.Tool, update.Constraint, update.Err)
+		default:
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", update.Tool, update.Constraint, update.Latest, update.Distance())
+		}
+		if update.Outdated() && update.Constraint != "" {
+			outdated++
+			if *write {
+				workspace.Prototools.Set("", update.Tool, update.Bumped())
+			}
+		}
+	}
+	if err := table.Flush(); err != nil {
+		log.Fatalf("Failed to print updates: %v", err)
+	}
+
+	if *write && outdated > 0 {
+		if err := workspace.Prototools.Write(); err != nil {
+			log.Fatalf("Failed to write .prototools: %v", err)
+		}
+		fmt.Printf("Bumped %d constraints in .prototools\n", outdated)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// gitRepository creates a local repository with the given tags to stand in
+// for an upstream.
+func gitRepository(t *testing.T, tags ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	commands := [][]string{
+		{"init", "--quiet"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "release"},
+	}
+	for _, tag := range tags {
+		commands = append(commands, []string{"tag", tag})
+	}
+	for _, args := range commands {
+		if output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	return dir
+}
+
+func TestStableVersions(t *testing.T) {
+	tags := []string{"v1.10.0", "v1.9.2", "kubernetes-1.11.0", "v1.12.0-rc.1", "latest", "v2"}
+
+	got, err := stableVersions(ResolveConfig{}, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Version{{1, 9, 2}, {1, 10, 0}, {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stableVersions() = %v, want %v", got, want)
+	}
+
+	got, err = stableVersions(ResolveConfig{GitTagPattern: "^(?:v|kubernetes-)(.*)$"}, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Version{{1, 9, 2}, {1, 10, 0}, {1, 11, 0}, {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stableVersions() with pattern = %v, want %v", got, want)
+	}
+
+	got, err = stableVersions(ResolveConfig{GitTagPattern: "^(?:kustomize/)(.*)$"}, []string{"kustomize/v5.4.1", "api/v0.17.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Version{{5, 4, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stableVersions() with a captured v = %v, want %v", got, want)
+	}
+}
+
+func TestTagVersion(t *testing.T) {
+	tests := []struct {
+		pattern string
+		tag     string
+		want    Version
+		ok      bool
+	}{
+		{defaultTagPattern, "v1.34.1", Version{1, 34, 1}, true},
+		{defaultTagPattern, "1.34.1", Version{1, 34, 1}, true},
+		{defaultTagPattern, "v1.35.0-rc.1", nil, false},
+		{"^(?:kustomize/)(.*)$", "kustomize/v5.4.1", Version{5, 4, 1}, true},
+		{"^(?:kustomize/)(.*)$", "api/v0.17.2", nil, false},
+		{"^release-(.*)$", "release-vv1", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := tagVersion(regexp.MustCompile(tt.pattern), tt.tag)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tagVersion(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.tag, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := parseVersion("v1.2.3"); ok {
+		t.Error("parseVersion(\"v1.2.3\") accepted a tag; only tagVersion drops the v")
+	}
+}
+
+func TestCheckUpdates(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	trivy := gitRepository(t, "v0.66.0", "v0.67.0", "v0.67.2", "v0.68.0", "v0.69.0-rc.1")
+	kubectl := gitRepository(t, "v1.34.1", "kubernetes-1.35.0")
+	shfmt := gitRepository(t, "v3.12.0")
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".prototools": `proto = "0.53.2"
+
+# Security scanners
+trivy = ">=0.67.0"   # keep in sync with CI
+kubectl =">=1.34.1"
+shfmt = "~3.12.0"
+`,
+		"toml/trivy.toml":   "name = \"trivy\"\n[resolve]\ngit-url = " + tomlString(trivy) + "\n",
+		"toml/kubectl.toml": "name = \"kubectl\"\n[resolve]\ngit-url = " + tomlString(kubectl) + "\ngit-tag-pattern = \"^(?:v|kubernetes-)(.*)$\"\n",
+		"toml/shfmt.toml":   "name = \"shfmt\"\n[resolve]\ngit-url = " + tomlString(shfmt) + "\n",
+		"toml/gone.toml":    "name = \"gone\"\n[resolve]\ngit-url = " + tomlString(filepath.Join(root, "missing")) + "\n",
+	})
+
+	workspace, err := loadWorkspace(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := checkUpdates(workspace, gitTagLister{})
+	if len(updates) != 4 {
+		t.Fatalf("checkUpdates() returned %d updates, want 4", len(updates))
+	}
+	if updates[0].Tool != "gone" || updates[0].Err == nil {
+		t.Errorf("gone = %+v, want an error for the missing repository", updates[0])
+	}
+
+	for _, tt := range []struct {
+		update   Update
+		latest   string
+		distance string
+		bumped   string
+	}{
+		{updates[1], "1.35.0", "minor, 1 release", ">=1.35.0"},
+		{updates[2], "3.12.0", "-", "~3.12.0"},
+		{updates[3], "0.68.0", "minor, 2 releases", ">=0.68.0"},
+	} {
+		if tt.update.Err != nil || tt.update.Latest.String() != tt.latest || tt.update.Distance() != tt.distance || tt.update.Bumped() != tt.bumped {
+			t.Errorf("%s = %+v (distance %q, bumped %q), want latest %s, distance %q, bumped %q",
+				tt.update.Tool, tt.update, tt.update.Distance(), tt.update.Bumped(), tt.latest, tt.distance, tt.bumped)
+		}
+		if tt.update.Outdated() {
+			workspace.Prototools.Set("", tt.update.Tool, tt.update.Bumped())
+		}
+	}
+
+	want := `proto = "0.53.2"
+
+# Security scanners
+trivy = ">=0.68.0"   # keep in sync with CI
+kubectl =">=1.35.0"
+shfmt = "~3.12.0"
+`
+	if got := workspace.Prototools.String(); got != want {
+		t.Errorf("bumped .prototools =\n%s\nwant\n%s", got, want)
+	}
+}
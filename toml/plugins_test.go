@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestPlugins installs every manifest in this directory and runs the checks
+// from its test spec, so a manifest without a spec fails instead of going
+// untested.
+func TestPlugins(t *testing.T) {
+	manifests, err := loadManifests(".")
+	if err != nil {
+		t.Fatalf("Failed to load manifests: %v", err)
+	}
+
+	for _, manifest := range manifests {
+		t.Run(manifest.Stem, func(t *testing.T) {
+			spec, err := readTestSpec(testSpecPath(".", manifest.Stem))
+			if err != nil {
+				t.Fatalf("Invalid test spec: %v", err)
+			}
+
+			fixtureDir, err := filepath.Abs(filepath.Join(testSpecDir, manifest.Stem))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			Run(TestConfig{Name: manifest.Stem, AfterInstall: spec.afterInstall(fixtureDir)})(t)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Prototools is a .prototools file kept as lines, so edits leave formatting
+// and comments outside the changed lines untouched.
+type Prototools struct {
+	Path  string
+	Lines []string
+
+	// Tools holds the top-level version constraints, Plugins the [plugins]
+	// table.
+	Tools   map[string]string
+	Plugins map[string]string
+}
+
+func readPrototools(path string) (*Prototools, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]any
+	if err := toml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	prototools := &Prototools{
+		Path:    path,
+		Lines:   strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"),
+		Tools:   map[string]string{},
+		Plugins: map[string]string{},
+	}
+	for key, value := range values {
+		switch value := value.(type) {
+		case string:
+			prototools.Tools[key] = value
+		case map[string]any:
+			if key != "plugins" {
+				continue
+			}
+			for name, locator := range value {
+				if locator, ok := locator.(string); ok {
+					prototools.Plugins[name] = locator
+				}
+			}
+		}
+	}
+	return prototools, nil
+}
+
+// tableBounds returns the line range of a table's body, from the line after
+// its header up to the next header, ignoring trailing blank lines.
+func (p *Prototools) tableBounds(name string) (start, end int, ok bool) {
+	header := "[" + name + "]"
+	for i, line := range p.Lines {
+		if strings.TrimSpace(line) != header {
+			continue
+		}
+		end = len(p.Lines)
+		for j := i + 1; j < len(p.Lines); j++ {
+			if strings.HasPrefix(strings.TrimSpace(p.Lines[j]), "[") {
+				end = j
+				break
+			}
+		}
+		for end > i+1 && strings.TrimSpace(p.Lines[end-1]) == "" {
+			end--
+		}
+		return i + 1, end, true
+	}
+	return 0, 0, false
+}
+
+// ReplaceTable swaps the body of a table for body, appending the table when
+// the file has none.
+func (p *Prototools) ReplaceTable(name string, body []string) {
+	start, end, ok := p.tableBounds(name)
+	if !ok {
+		p.Lines = append(p.Lines, "", "["+name+"]")
+		p.Lines = append(p.Lines, body...)
+		return
+	}
+
+	lines := append([]string{}, p.Lines[:start]...)
+	lines = append(lines, body...)
+	p.Lines = append(lines, p.Lines[end:]...)
+}
+
+func (p *Prototools) String() string {
+	return strings.Join(p.Lines, "\n") + "\n"
+}
+
+func (p *Prototools) Write() error {
+	return os.WriteFile(filepath.Clean(p.Path), []byte(p.String()), 0o600)
+}
+
+// pluginLocator is how .prototools points at a manifest in this directory.
+func pluginLocator(stem string) string {
+	return fmt.Sprintf("file://./toml/%s.toml", stem)
+}
+
+// keyLine matches a key/value line, capturing the key and everything up to
+// the opening quote of a string value.
+var keyLine = regexp.MustCompile(`^\s*"?([A-Za-z0-9_-]+)"?(\s*=\s*)"`)
+
+// keyLines returns the indexes of key/value lines in a table, or in the
+// top-level section when table is empty.
+func (p *Prototools) keyLines(table string) []int {
+	start, end := 0, len(p.Lines)
+	if table != "" {
+		var ok bool
+		if start, end, ok = p.tableBounds(table); !ok {
+			return nil
+		}
+	} else {
+		for i, line := range p.Lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "[") {
+				end = i
+				break
+			}
+		}
+	}
+
+	var indexes []int
+	for i := start; i < end; i++ {
+		if keyLine.MatchString(p.Lines[i]) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Set writes a string value for key in table ("" for the top level). An
+// existing value is replaced in place, keeping the spacing around "=" and
+// any trailing comment; a new key is inserted before the first key that
+// sorts after it and the comments above that key.
+func (p *Prototools) Set(table, key, value string) {
+	indexes := p.keyLines(table)
+	for _, index := range indexes {
+		if keyLine.FindStringSubmatch(p.Lines[index])[1] == key {
+			p.Lines[index] = replaceValue(p.Lines[index], value)
+			p.record(table, key, value)
+			return
+		}
+	}
+
+	entry := fmt.Sprintf("%s = %s", key, tomlString(value))
+	insert := -1
+	for _, index := range indexes {
+		existing := keyLine.FindStringSubmatch(p.Lines[index])[1]
+		if existing > key && !builtinTools[existing] {
+			insert = index
+			break
+		}
+	}
+	switch {
+	case insert >= 0:
+		for insert > 0 && strings.HasPrefix(strings.TrimSpace(p.Lines[insert-1]), "#") {
+			insert--
+		}
+	case len(indexes) > 0:
+		insert = indexes[len(indexes)-1] + 1
+	case table == "":
+		insert = 0
+	default:
+		p.ReplaceTable(table, []string{entry})
+		p.record(table, key, value)
+		return
+	}
+
+	p.Lines = append(p.Lines[:insert], append([]string{entry}, p.Lines[insert:]...)...)
+	p.record(table, key, value)
+}
+
+// Delete removes key's line from table ("" for the top level), leaving the
+// lines around it untouched.
+func (p *Prototools) Delete(table, key string) {
+	for _, index := range p.keyLines(table) {
+		if keyLine.FindStringSubmatch(p.Lines[index])[1] == key {
+			p.Lines = append(p.Lines[:index], p.Lines[index+1:]...)
+			break
+		}
+	}
+	switch table {
+	case "":
+		delete(p.Tools, key)
+	case "plugins":
+		delete(p.Plugins, key)
+	}
+}
+
+func (p *Prototools) record(table, key, value string) {
+	switch table {
+	case "":
+		p.Tools[key] = value
+	case "plugins":
+		p.Plugins[key] = value
+	}
+}
+
+// replaceValue swaps the string value on a key/value line.
+func replaceValue(line, value string) string {
+	prefix := keyLine.FindString(line)
+	rest := line[len(prefix):]
+
+	end := 0
+	for end < len(rest) && rest[end] != '"' {
+		if rest[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(rest) {
+		return prefix[:len(prefix)-1] + tomlString(value)
+	}
+	return prefix[:len(prefix)-1] + tomlString(value) + rest[end+1:]
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// archToken and osToken find the architecture and operating system in a
+// release asset name. Matches must sit between separators so "win" does not
+// match "darwin".
+var (
+	archToken = regexp.MustCompile(`(?i)(?:^|[-_.])(x86_64|amd64|x64|64bit|aarch64|arm64)(?:$|[-_.])`)
+	osToken   = regexp.MustCompile(`(?i)(?:^|[-_.])(linux|darwin|macos|apple|osx|windows|win64|win)(?:$|[-_.])`)
+)
+
+var protoArch = map[string]string{
+	"x86_64": "x86_64", "amd64": "x86_64", "x64": "x86_64", "64bit": "x86_64",
+	"aarch64": "aarch64", "arm64": "aarch64",
+}
+
+var assetPlatform = map[string]string{
+	"linux": "linux", "darwin": "macos", "macos": "macos", "apple": "macos", "osx": "macos",
+	"windows": "windows", "win64": "windows", "win": "windows",
+}
+
+// formatRank orders download formats by preference, ahead of bare
+// binaries. Assets with a nonDownloads extension are packages, signatures or
+// metadata.
+var (
+	formatRank   = []string{".tar.gz", ".tgz", ".zip", ".tar.xz", ".gz", ".exe"}
+	nonDownloads = []string{".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg", ".sig", ".pem", ".asc", ".sbom", ".json", ".jsonl", ".txt", ".sha256", ".sha256sum", ".sha512", ".md5", ".bundle", ".spdx", ".cdx"}
+)
+
+func downloadFormat(name string) (int, bool) {
+	lower := strings.ToLower(name)
+	for rank, extension := range formatRank {
+		if strings.HasSuffix(lower, extension) {
+			return rank, true
+		}
+	}
+	for _, extension := range nonDownloads {
+		if strings.HasSuffix(lower, extension) {
+			return 0, false
+		}
+	}
+	return len(formatRank), true
+}
+
+func isChecksumAsset(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "checksums") || strings.HasPrefix(lower, "sha256sums") ||
+		strings.HasSuffix(lower, ".sha256") || strings.HasSuffix(lower, ".sha256sum")
+}
+
+// Release is the part of a GitHub release the scaffold needs.
+type Release struct {
+	Tag    string
+	Assets []string
+}
+
+// readRelease accepts a GitHub release object, its assets array, or a plain
+// array of asset names.
+func readRelease(path string) (Release, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return Release{}, err
+	}
+
+	type asset struct {
+		Name string `json:"name"`
+	}
+	var release struct {
+		TagName string  `json:"tag_name"`
+		Assets  []asset `json:"assets"`
+	}
+	var assets []asset
+	var names []string
+
+	switch {
+	case json.Unmarshal(content, &names) == nil:
+		return Release{Assets: names}, nil
+	case json.Unmarshal(content, &assets) == nil:
+	case json.Unmarshal(content, &release) == nil:
+		assets = release.Assets
+	default:
+		return Release{}, fmt.Errorf("%s is not a GitHub release, an assets array or a list of asset names", path)
+	}
+
+	for _, asset := range assets {
+		names = append(names, asset.Name)
+	}
+	return Release{Tag: release.TagName, Assets: names}, nil
+}
+
+type platformAsset struct {
+	name string
+	arch string
+	rank int
+}
+
+// inferManifest builds a manifest from a release's asset names. It returns
+// notes on anything a reviewer should check by hand.
+// The tag decides whether download URLs use a v prefix.
+func inferManifest(name, repository, tag string, assets []string) (Manifest, []string, error) {
+	version := strings.TrimPrefix(tag, "v")
+	if version == "" {
+		return Manifest{}, nil, errors.New("the release tag is unknown; pass -tag")
+	}
+	tagPrefix := strings.TrimSuffix(tag, version)
+
+	var notes []string
+	candidates := map[string][]platformAsset{}
+	for _, asset := range assets {
+		if isChecksumAsset(asset) {
+			continue
+		}
+		rank, ok := downloadFormat(asset)
+		osMatch, archMatch := osToken.FindStringSubmatch(asset), archToken.FindStringSubmatch(asset)
+		if !ok || osMatch == nil || archMatch == nil {
+			continue
+		}
+		if strings.Contains(strings.ToLower(asset), "musl") {
+			rank += len(formatRank) + 1
+		}
+		platform := assetPlatform[strings.ToLower(osMatch[1])]
+		candidates[platform] = append(candidates[platform], platformAsset{name: asset, arch: archMatch[1], rank: rank})
+	}
+	if len(candidates) == 0 {
+		return Manifest{}, nil, fmt.Errorf("no release asset names an operating system, an architecture and version %s", version)
+	}
+
+	manifest := Manifest{
+		Name:     name,
+		Type:     "cli",
+		Platform: map[string]PlatformConfig{},
+		Install: InstallConfig{
+			DownloadURL: fmt.Sprintf("https://github.com/%s/releases/download/%s{version}/{download_file}", repository, tagPrefix),
+			Arch:        map[string]string{},
+		},
+		Resolve: ResolveConfig{GitURL: "https://github.com/" + repository},
+	}
+
+	for _, platform := range sortedKeys(candidates) {
+		chosen := map[string]platformAsset{}
+		for _, candidate := range candidates[platform] {
+			arch := protoArch[strings.ToLower(candidate.arch)]
+			if current, ok := chosen[arch]; !ok || candidate.rank < current.rank {
+				chosen[arch] = candidate
+			}
+		}
+
+		var template string
+		for _, arch := range []string{"x86_64", "aarch64"} {
+			asset, ok := chosen[arch]
+			if !ok {
+				continue
+			}
+			assetTemplate, err := templateAsset(asset.name, version)
+			if err != nil {
+				return Manifest{}, nil, err
+			}
+			if template == "" {
+				template = assetTemplate
+			} else if assetTemplate != template {
+				notes = append(notes, fmt.Sprintf("%s: %s does not follow %s; only x86_64 will resolve", platform, asset.name, template))
+				continue
+			}
+
+			if existing, ok := manifest.Install.Arch[arch]; ok && existing != asset.arch {
+				notes = append(notes, fmt.Sprintf("%s: names %s %q but another platform uses %q; [install.arch] is shared", platform, arch, asset.arch, existing))
+			} else if asset.arch != arch {
+				manifest.Install.Arch[arch] = asset.arch
+			}
+		}
+
+		config := PlatformConfig{DownloadFile: template, BinPath: name}
+		if platform == "windows" {
+			config.BinPath = name + ".exe"
+		}
+		config.ChecksumFile = checksumTemplate(assets, chosenNames(chosen), template, version)
+		if config.ChecksumFile != "" {
+			manifest.Install.ChecksumURL = strings.Replace(manifest.Install.DownloadURL, "{download_file}", "{checksum_file}", 1)
+		}
+		manifest.Platform[platform] = config
+	}
+
+	for _, platform := range platformOrder {
+		if _, ok := manifest.Platform[platform]; !ok {
+			notes = append(notes, fmt.Sprintf("%s: no release asset found", platform))
+		}
+	}
+	for _, platform := range manifest.orderedPlatforms() {
+		if manifest.Platform[platform].ChecksumFile == "" {
+			notes = append(notes, fmt.Sprintf("%s: no checksum file found; downloads are not verified", platform))
+		}
+	}
+	return manifest, notes, nil
+}
+
+// templateAsset turns an asset name into a download-file template. It fails
+// when replacing the version also removed the architecture from the name.
+func templateAsset(asset, version string) (string, error) {
+	template := strings.ReplaceAll(asset, version, "{version}")
+	match := archToken.FindStringSubmatchIndex(template)
+	if match == nil {
+		return "", fmt.Errorf("cannot tell the architecture from version %s in %s", version, asset)
+	}
+	return template[:match[2]] + "{arch}" + template[match[3]:], nil
+}
+
+func chosenNames(chosen map[string]platformAsset) []string {
+	names := make([]string, 0, len(chosen))
+	for _, arch := range sortedKeys(chosen) {
+		names = append(names, chosen[arch].name)
+	}
+	return names
+}
+
+// checksumTemplate prefers a checksum published next to each asset, then a
+// checksum file covering the whole release.
+func checksumTemplate(assets, chosen []string, template, version string) string {
+	for _, suffix := range []string{".sha256", ".sha256sum"} {
+		for _, asset := range chosen {
+			if contains(assets, asset+suffix) {
+				return template + suffix
+			}
+		}
+	}
+	for _, asset := range assets {
+		lower := strings.ToLower(asset)
+		if strings.Contains(lower, "checksums") || strings.HasPrefix(lower, "sha256sums") {
+			return strings.ReplaceAll(asset, version, "{version}")
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Scaffold is a new plugin ready to be written into a workspace.
+type Scaffold struct {
+	Manifest Manifest
+	Command  string
+	Version  string
+}
+
+// writeScaffold creates the manifest and its test spec and adds the plugin to
+// .prototools. Existing files are only replaced when force is set.
+func writeScaffold(root string, scaffold Scaffold, force bool) ([]string, error) {
+	name := scaffold.Manifest.Name
+	files := map[string]string{
+		filepath.Join(root, "toml", name+".toml"):       renderManifest(scaffold.Manifest),
+		testSpecPath(filepath.Join(root, "toml"), name): renderTestSpec(scaffold.Command),
+	}
+
+	paths := sortedKeys(files)
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil && !force {
+			return nil, fmt.Errorf("%s already exists; pass -force to replace it", path)
+		}
+	}
+
+	prototools, err := readPrototools(filepath.Join(root, ".prototools"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "toml", testSpecDir), 0o750); err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(files[path]), 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	prototools.Set("", name, ">="+scaffold.Version)
+	prototools.Set("plugins", name, pluginLocator(name))
+	if err := prototools.Write(); err != nil {
+		return nil, err
+	}
+	return append(paths, prototools.Path), nil
+}
+
+func runScaffold(args []string) {
+	flags := flag.NewFlagSet("scaffold", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	name := flags.String("name", "", "tool name, used for the manifest, test and .prototools entries")
+	repository := flags.String("repo", "", "upstream GitHub repository as owner/name")
+	assets := flags.String("assets", "", "JSON file with the release: a GitHub release object, its assets, or a list of asset names")
+	tag := flags.String("tag", "", "release tag the assets belong to, such as v1.2.3 (defaults to tag_name in -assets)")
+	command := flags.String("command", "", "command the test runs after installing (defaults to \"<name> --version\")")
+	force := flags.Bool("force", false, "replace an existing manifest and test spec")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse scaffold flags: %v", err)
+	}
+
+	switch {
+	case *name == "":
+		log.Fatalf("scaffold needs -name")
+	case !strings.Contains(*repository, "/"):
+		log.Fatalf("scaffold needs -repo as owner/name")
+	case *assets == "":
+		log.Fatalf("scaffold needs -assets with the release asset list")
+	}
+
+	release, err := readRelease(*assets)
+	if err != nil {
+		log.Fatalf("Failed to read release assets: %v", err)
+	}
+	if *tag == "" {
+		*tag = release.Tag
+	}
+	if *command == "" {
+		*command = *name + " --version"
+	}
+
+	manifest, notes, err := inferManifest(*name, strings.TrimPrefix(*repository, "https://github.com/"), *tag, release.Assets)
+	if err != nil {
+		log.Fatalf("Failed to infer manifest: %v", err)
+	}
+
+	written, err := writeScaffold(workspaceRoot(*root), Scaffold{Manifest: manifest, Command: *command, Version: strings.TrimPrefix(*tag, "v")}, *force)
+	if err != nil {
+		log.Fatalf("Failed to write scaffold: %v", err)
+	}
+	for _, path := range written {
+		fmt.Printf("wrote %s\n", path)
+	}
+	for _, note := range notes {
+		fmt.Printf("check: %s\n", note)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInferManifest(t *testing.T) {
+	tests := []struct {
+		name   string
+		repo   string
+		tag    string
+		assets []string
+	}{
+		{
+			name: "trivy",
+			repo: "aquasecurity/trivy",
+			tag:  "v0.67.0",
+			assets: []string{
+				"trivy_0.67.0_checksums.txt",
+				"trivy_0.67.0_FreeBSD-64bit.tar.gz",
+				"trivy_0.67.0_Linux-32bit.tar.gz",
+				"trivy_0.67.0_Linux-64bit.deb",
+				"trivy_0.67.0_Linux-64bit.tar.gz",
+				"trivy_0.67.0_Linux-ARM64.tar.gz",
+				"trivy_0.67.0_Linux-ARM64.tar.gz.sigstore.json",
+				"trivy_0.67.0_macOS-64bit.tar.gz",
+				"trivy_0.67.0_macOS-ARM64.tar.gz",
+				"trivy_0.67.0_windows-64bit.zip",
+			},
+		},
+		{
+			name: "hadolint",
+			repo: "hadolint/hadolint",
+			tag:  "v2.14.0",
+			assets: []string{
+				"hadolint-linux-arm64",
+				"hadolint-linux-arm64.sha256",
+				"hadolint-linux-x86_64",
+				"hadolint-linux-x86_64.sha256",
+				"hadolint-macos-arm64",
+				"hadolint-macos-arm64.sha256",
+				"hadolint-macos-x86_64",
+				"hadolint-macos-x86_64.sha256",
+				"hadolint-windows-x86_64.exe",
+				"hadolint-windows-x86_64.exe.sha256",
+			},
+		},
+		{
+			name: "kubens",
+			repo: "ahmetb/kubectx",
+			tag:  "v0.9.5",
+			assets: []string{
+				"checksums.txt",
+				"kubectx_v0.9.5_linux_x86_64.tar.gz",
+				"kubens_v0.9.5_darwin_arm64.tar.gz",
+				"kubens_v0.9.5_darwin_x86_64.tar.gz",
+				"kubens_v0.9.5_linux_arm64.tar.gz",
+				"kubens_v0.9.5_linux_x86_64.tar.gz",
+				"kubens_v0.9.5_windows_x86_64.zip",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var assets []string
+			for _, asset := range tt.assets {
+				if tt.name != "kubens" || strings.HasPrefix(asset, "kubens") || asset == "checksums.txt" {
+					assets = append(assets, asset)
+				}
+			}
+
+			got, _, err := inferManifest(tt.name, tt.repo, tt.tag, assets)
+			if err != nil {
+				t.Fatalf("inferManifest() error = %v", err)
+			}
+			want, err := readManifest(tt.name + ".toml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.Platform, want.Platform) {
+				t.Errorf("Platform = %+v, want %+v", got.Platform, want.Platform)
+			}
+			if got.Install.DownloadURL != want.Install.DownloadURL || got.Install.ChecksumURL != want.Install.ChecksumURL {
+				t.Errorf("Install = %+v, want %+v", got.Install, want.Install)
+			}
+			for _, arch := range []string{"x86_64", "aarch64"} {
+				if got.Install.Arch[arch] != want.Install.Arch[arch] {
+					t.Errorf("Install.Arch[%s] = %q, want %q", arch, got.Install.Arch[arch], want.Install.Arch[arch])
+				}
+			}
+			if got.Resolve != want.Resolve {
+				t.Errorf("Resolve = %+v, want %+v", got.Resolve, want.Resolve)
+			}
+		})
+	}
+}
+
+func TestInferManifestNotes(t *testing.T) {
+	_, notes, err := inferManifest("tool", "owner/tool", "1.0.0", []string{"tool-1.0.0-linux-amd64.tar.gz"})
+	if err != nil {
+		t.Fatalf("inferManifest() error = %v", err)
+	}
+
+	want := []string{
+		"macos: no release asset found",
+		"windows: no release asset found",
+		"linux: no checksum file found; downloads are not verified",
+	}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("notes = %q, want %q", notes, want)
+	}
+
+	if _, _, err := inferManifest("tool", "owner/tool", "v1.0.0", []string{"tool.deb", "README.txt"}); err == nil {
+		t.Error("inferManifest() without usable assets should fail")
+	}
+	if _, _, err := inferManifest("tool", "owner/tool", "v64", []string{"tool-linux-x86_64.tar.gz"}); err == nil {
+		t.Error("inferManifest() should fail when the version hides the architecture")
+	}
+}
+
+func TestWriteScaffold(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		lockFileName:  "[plugins.some-tool]\nversion = \"2.1.0\"\n",
+		".prototools": "proto = \"0.53.2\"\n\nactionlint = \">=1.7.7\"\nzizmor = \">=1.14.2\"\n\n[plugins]\nactionlint = \"file://./toml/actionlint.toml\"\nzizmor = \"file://./toml/zizmor.toml\"\n",
+	})
+
+	manifest, _, err := inferManifest("some-tool", "owner/some-tool", "v2.1.0", []string{
+		"some-tool_2.1.0_checksums.txt",
+		"some-tool_2.1.0_linux_amd64.tar.gz",
+		"some-tool_2.1.0_darwin_arm64.tar.gz",
+		"some-tool_2.1.0_windows_amd64.zip",
+	})
+	if err != nil {
+		t.Fatalf("inferManifest() error = %v", err)
+	}
+
+	scaffold := Scaffold{Manifest: manifest, Command: "some-tool version", Version: "2.1.0"}
+	if _, err := writeScaffold(root, scaffold, false); err != nil {
+		t.Fatalf("writeScaffold() error = %v", err)
+	}
+	if _, err := writeScaffold(root, scaffold, false); err == nil {
+		t.Error("writeScaffold() should refuse to replace existing files")
+	}
+
+	prototools, err := os.ReadFile(filepath.Join(root, ".prototools"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "proto = \"0.53.2\"\n\nactionlint = \">=1.7.7\"\nsome-tool = \">=2.1.0\"\nzizmor = \">=1.14.2\"\n\n[plugins]\nactionlint = \"file://./toml/actionlint.toml\"\nsome-tool = \"file://./toml/some-tool.toml\"\nzizmor = \"file://./toml/zizmor.toml\"\n"
+	if string(prototools) != want {
+		t.Errorf(".prototools =\n%s\nwant\n%s", prototools, want)
+	}
+
+	workspace, err := loadWorkspace(root)
+	if err != nil {
+		t.Fatalf("loadWorkspace() error = %v", err)
+	}
+	for _, problem := range checkSync(workspace) {
+		if problem.Tool == "some-tool" {
+			t.Errorf("scaffolded plugin is out of sync: %s", problem)
+		}
+	}
+
+	written, err := readManifest(filepath.Join(root, "toml", "some-tool.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written, manifest) {
+		t.Errorf("rendered manifest reads back as %+v, want %+v", written, manifest)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// builtinTools are versioned in .prototools without a plugin from this
+// repository.
+var builtinTools = map[string]bool{"proto": true, "moon": true}
+
+// SyncProblem is one disagreement between the manifests, their test specs and
+// .prototools.
+type SyncProblem struct {
+	Tool    string
+	Message string
+}
+
+func (p SyncProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Tool, p.Message)
+}
+
+// Workspace is the set of sources the sync check cross-references.
+type Workspace struct {
+	Root       string
+	Manifests  []ManifestFile
+	Specs      []string
+	Checksums  []string
+	Prototools *Prototools
+	Lock       *Lockfile
+}
+
+func loadWorkspace(root string) (*Workspace, error) {
+	manifestDir := filepath.Join(root, "toml")
+
+	manifests, err := loadManifests(manifestDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifests: %w", err)
+	}
+	specs, err := findTestSpecs(manifestDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find test specs: %w", err)
+	}
+	checksums, err := tomlStems(filepath.Join(manifestDir, checksumDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vendored checksums: %w", err)
+	}
+	prototools, err := readPrototools(filepath.Join(root, ".prototools"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .prototools: %w", err)
+	}
+	lock, err := readLockfile(lockPath(root))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Workspace{Root: root, Manifests: manifests, Specs: specs, Checksums: checksums, Prototools: prototools, Lock: lock}, nil
+}
+
+// checkSync reports manifests without a valid test spec, [plugins] entry,
+// version constraint, lock entry or way to verify downloads, names that disagree between
+// the sources, invalid vendored checksums, and entries that point at nothing.
+func checkSync(workspace *Workspace) []SyncProblem {
+	var problems []SyncProblem
+	report := func(tool, format string, args ...any) {
+		problems = append(problems, SyncProblem{Tool: tool, Message: fmt.Sprintf(format, args...)})
+	}
+
+	tools := map[string]bool{}
+	for _, manifest := range workspace.Manifests {
+		tool := manifest.Stem
+		tools[tool] = true
+
+		if manifest.Name != tool {
+			report(tool, "%s.toml declares name %q; proto uses the file name, so they must match", tool, manifest.Name)
+		}
+
+		if !contains(workspace.Specs, tool) {
+			report(tool, "has no test spec at toml/%s/%s.toml", testSpecDir, tool)
+		} else if _, err := readTestSpec(testSpecPath(filepath.Dir(manifest.Path), tool)); err != nil {
+			report(tool, "invalid test spec: %v", err)
+		}
+
+		switch locator, ok := workspace.Prototools.Plugins[tool]; {
+		case !ok:
+			report(tool, "missing from [plugins] in .prototools")
+		case locator != pluginLocator(tool):
+			report(tool, "[plugins] points at %q, expected %q", locator, pluginLocator(tool))
+		}
+
+		if _, ok := workspace.Prototools.Tools[tool]; !ok {
+			report(tool, "has no version constraint in .prototools")
+		}
+
+		if !manifest.checksumVerified() && !contains(workspace.Checksums, tool) {
+			report(tool, "downloads are not verified: add a checksum-url or run: go -C toml run . checksums -tool %s", tool)
+		}
+
+		if _, ok := workspace.Lock.Plugins[tool]; !ok {
+			report(tool, "has no entry in %s; run: go -C toml run . lock -tools %s", lockFileName, tool)
+		}
+	}
+
+	for _, spec := range workspace.Specs {
+		if !tools[spec] {
+			report(spec, "test spec toml/%s/%s.toml has no manifest", testSpecDir, spec)
+		}
+	}
+	for _, name := range workspace.Checksums {
+		if !tools[name] {
+			report(name, "vendored checksums toml/%s/%s.toml have no manifest", checksumDir, name)
+		} else if _, err := readVendoredChecksums(vendoredChecksumPath(filepath.Join(workspace.Root, "toml"), name)); err != nil {
+			report(name, "invalid vendored checksums: %v", err)
+		}
+	}
+	for name := range workspace.Lock.Plugins {
+		if !tools[name] {
+			report(name, "locked in %s but has no manifest", lockFileName)
+		}
+	}
+	for name, locator := range workspace.Prototools.Plugins {
+		if !tools[name] {
+			report(name, "[plugins] entry %q has no manifest", locator)
+		}
+	}
+	for name := range workspace.Prototools.Tools {
+		if !tools[name] && !builtinTools[name] {
+			report(name, "version constraint in .prototools has no plugin")
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Tool < problems[j].Tool })
+	return problems
+}
+
+// fixPlugins points each manifest's [plugins] entry at it and removes
+// entries without a manifest. Entries are edited in place, like outdated
+// -write does, so comments and ordering in the table are kept.
+func fixPlugins(workspace *Workspace) {
+	tools := map[string]bool{}
+	for _, manifest := range workspace.Manifests {
+		tools[manifest.Stem] = true
+		if locator := pluginLocator(manifest.Stem); workspace.Prototools.Plugins[manifest.Stem] != locator {
+			workspace.Prototools.Set("plugins", manifest.Stem, locator)
+		}
+	}
+	for _, name := range sortedKeys(workspace.Prototools.Plugins) {
+		if !tools[name] {
+			workspace.Prototools.Delete("plugins", name)
+		}
+	}
+}
+
+func runSync(args []string) {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	root := flags.String("root", "", "workspace root containing .prototools (defaults to the nearest parent that has one)")
+	fix := flags.Bool("fix", false, "point [plugins] entries in .prototools at the manifests, removing entries without one")
+	if err := flags.Parse(args); err != nil {
+		log.Fatalf("Failed to parse sync flags: %v", err)
+	}
+
+	workspace, err := loadWorkspace(workspaceRoot(*root))
+	if err != nil {
+		log.Fatalf("Failed to load workspace: %v", err)
+	}
+
+	if *fix {
+		fixPlugins(workspace)
+		if err := workspace.Prototools.Write(); err != nil {
+			log.Fatalf("Failed to write .prototools: %v", err)
+		}
+	}
+
+	problems := checkSync(workspace)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "found %d problem(s)\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Printf("%d manifests, test specs and .prototools entries agree\n", len(workspace.Manifests))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files under root from a map of slash-separated paths.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// verifiedManifest is a manifest whose downloads proto checks against a
+// checksum file.
+func verifiedManifest(name string) string {
+	return "name = \"" + name + "\"\n\n[platform.linux]\ndownload-file = \"" + name + ".tar.gz\"\nchecksum-file = \"SHA256SUMS\"\n\n[install]\ndownload-url = \"https://example.com/{download_file}\"\nchecksum-url = \"https://example.com/{checksum_file}\"\n"
+}
+
+const versionSpec = "[[command]]\nrun = \"tool --version\"\n"
+
+const syncPrototools = `proto = "0.53.2"
+
+# Tools
+kubectx = ">=0.9.5"
+trivy = ">=0.67.0"
+stale = ">=1.0.0"
+
+[plugins]
+kubectx = "file://./toml/kubectx.toml"
+stale = "file://./toml/stale.toml"
+# pinned to a fork
+trivy = "file://./toml/trivy-fork.toml"
+
+[settings]
+auto-install = true
+`
+
+func TestCheckSync(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".prototools":                       syncPrototools,
+		"toml/kubectx.toml":                 verifiedManifest("kubectx"),
+		"toml/testdata/kubectx.toml":        versionSpec,
+		"toml/kubens.toml":                  `name = "kubens"`,
+		"toml/testdata/kubens.toml":         "[[command]]\nrun = \"kubens --version\"\nexit = 0\n",
+		"toml/trivy.toml":                   `name = "trivy-cli"`,
+		"toml/terraform-docs.toml":          `name = "terraform-docs"`,
+		"toml/testdata/terraform-docs.toml": versionSpec,
+		"toml/testdata/gone.toml":           versionSpec,
+		lockFileName:                        "[plugins.kubectx]\nversion = \"0.9.5\"\n\n[plugins.retired]\nversion = \"1.0.0\"\n",
+	})
+
+	workspace, err := loadWorkspace(root)
+	if err != nil {
+		t.Fatalf("loadWorkspace() error = %v", err)
+	}
+
+	var got []string
+	for _, problem := range checkSync(workspace) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		`gone: test spec toml/testdata/gone.toml has no manifest`,
+		`kubens: invalid test spec: ` + filepath.Join(root, "toml", "testdata", "kubens.toml") + `: unknown keys command.exit`,
+		`kubens: missing from [plugins] in .prototools`,
+		`kubens: has no version constraint in .prototools`,
+		`kubens: downloads are not verified: add a checksum-url or run: go -C toml run . checksums -tool kubens`,
+		`kubens: has no entry in proto-plugins.lock; run: go -C toml run . lock -tools kubens`,
+		`retired: locked in proto-plugins.lock but has no manifest`,
+		`stale: [plugins] entry "file://./toml/stale.toml" has no manifest`,
+		`stale: version constraint in .prototools has no plugin`,
+		`terraform-docs: missing from [plugins] in .prototools`,
+		`terraform-docs: has no version constraint in .prototools`,
+		`terraform-docs: downloads are not verified: add a checksum-url or run: go -C toml run . checksums -tool terraform-docs`,
+		`terraform-docs: has no entry in proto-plugins.lock; run: go -C toml run . lock -tools terraform-docs`,
+		`trivy: trivy.toml declares name "trivy-cli"; proto uses the file name, so they must match`,
+		`trivy: has no test spec at toml/testdata/trivy.toml`,
+		`trivy: [plugins] points at "file://./toml/trivy-fork.toml", expected "file://./toml/trivy.toml"`,
+		`trivy: downloads are not verified: add a checksum-url or run: go -C toml run . checksums -tool trivy`,
+		`trivy: has no entry in proto-plugins.lock; run: go -C toml run . lock -tools trivy`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkSync() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFixPlugins(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".prototools":       syncPrototools,
+		"toml/kubectx.toml": `name = "kubectx"`,
+		"toml/kubens.toml":  `name = "kubens"`,
+		"toml/trivy.toml":   `name = "trivy"`,
+	})
+
+	workspace, err := loadWorkspace(root)
+	if err != nil {
+		t.Fatalf("loadWorkspace() error = %v", err)
+	}
+	fixPlugins(workspace)
+
+	want := `proto = "0.53.2"
+
+# Tools
+kubectx = ">=0.9.5"
+trivy = ">=0.67.0"
+stale = ">=1.0.0"
+
+[plugins]
+kubectx = "file://./toml/kubectx.toml"
+kubens = "file://./toml/kubens.toml"
+# pinned to a fork
+trivy = "file://./toml/trivy.toml"
+
+[settings]
+auto-install = true
+`
+	if got := workspace.Prototools.String(); got != want {
+		t.Errorf("fixPlugins() wrote\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,25 +1,20 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
-
-	"github.com/BurntSushi/toml"
 )
 
-type Plugin struct {
-	Name     string                 `toml:"name"`
-	Platform map[string]interface{} `toml:"platform"`
-}
-
 type TestConfig struct {
 	Name         string
 	AfterInstall func(t *testing.T, shell *Shell) error
@@ -57,12 +52,17 @@ func Run(config TestConfig) func(*testing.T) {
 		var shell *Shell
 
 		defer func() {
+			if t.Failed() && result.Error == nil {
+				result.Error = fmt.Errorf("plugin test %s failed", config.Name)
+			}
 			finalizeTestResult(result, shell)
 		}()
 
 		printTestHeader(config.Name)
 
 		plugin, tomlPathSource := loadPluginConfig(t, config.Name)
+		locked, isLocked := loadLockedPlugin(t, tomlPathSource, config.Name)
+		vendored := loadVendoredChecksums(t, tomlPathSource, config.Name)
 		platform := getPlatform()
 		supportPlatforms := extractSupportedPlatforms(plugin)
 
@@ -75,6 +75,9 @@ func Run(config TestConfig) func(*testing.T) {
 		if skip {
 			t.Skipf("Platform %s not supported by plugin %s", platform, config.Name)
 		}
+		if !plugin.checksumVerified() && len(vendored) == 0 {
+			t.Fatalf("%s.toml has no checksum-url and no vendored checksums; run: go -C toml run . checksums -tool %s", config.Name, config.Name)
+		}
 
 		tempDir := createTempDirectory(t, config.Name)
 		defer cleanupTempDirectory(tempDir)
@@ -84,7 +87,21 @@ func Run(config TestConfig) func(*testing.T) {
 		defer restoreOriginalDirectory(originalDir)
 
 		shell = initializeShell(t)
-		executePluginInstallation(shell, config.Name)
+		version := "latest"
+		switch {
+		case isLocked:
+			version = locked.Version
+		case len(vendored) > 0:
+			version = vendored.versions()[0]
+		}
+		hash := newInstalledHasher(t)
+		executePluginInstallation(shell, config.Name, version)
+		if isLocked {
+			verifyLockedArtifact(shell, plugin, locked, hash)
+		}
+		if len(vendored) > 0 {
+			verifyVendoredArtifact(shell, plugin, vendored, version, hash)
+		}
 		executeAfterInstallTests(t, shell, config.AfterInstall)
 	}
 }
@@ -108,8 +125,8 @@ func finalizeTestResult(result *TestResult, shell *Shell) {
 	printTestResult(result)
 }
 
-func loadPluginConfig(t *testing.T, pluginName string) (Plugin, string) {
-	_, filename, _, ok := runtime.Caller(2)
+func loadPluginConfig(t *testing.T, pluginName string) (Manifest, string) {
+	_, filename, _, ok := runtime.Caller(0)
 	if !ok {
 		t.Fatal("Could not get caller information")
 	}
@@ -120,25 +137,39 @@ func loadPluginConfig(t *testing.T, pluginName string) (Plugin, string) {
 		tomlPathSource = filepath.Clean(tomlPathSource)
 	}
 
-	content, err := os.ReadFile(tomlPathSource)
+	plugin, err := readManifest(tomlPathSource)
 	if err != nil {
 		t.Fatalf("Failed to read %s.toml: %v", pluginName, err)
 	}
 
-	var plugin Plugin
-	if err := toml.Unmarshal(content, &plugin); err != nil {
-		t.Fatalf("Failed to parse %s.toml: %v", pluginName, err)
-	}
-
 	return plugin, tomlPathSource
 }
 
-func extractSupportedPlatforms(plugin Plugin) []string {
-	supportPlatforms := make([]string, 0, len(plugin.Platform))
-	for platformName := range plugin.Platform {
-		supportPlatforms = append(supportPlatforms, platformName)
+// loadLockedPlugin reads the plugin's entry from the lockfile at the
+// workspace root. Without an entry the newest vendored release is tested, or
+// the latest one when proto verifies downloads against a checksum-url.
+func loadLockedPlugin(t *testing.T, tomlPathSource, pluginName string) (LockedPlugin, bool) {
+	lock, err := readLockfile(lockPath(filepath.Dir(filepath.Dir(tomlPathSource))))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", lockFileName, err)
 	}
-	return supportPlatforms
+	locked, ok := lock.Plugins[pluginName]
+	return locked, ok
+}
+
+// loadVendoredChecksums reads toml/checksums/<name>.toml. Manifests without a
+// checksum-url need it, and the installed release must have a vendored
+// checksum for the current target.
+func loadVendoredChecksums(t *testing.T, tomlPathSource, pluginName string) VendoredChecksums {
+	checksums, err := readVendoredChecksums(vendoredChecksumPath(filepath.Dir(tomlPathSource), pluginName))
+	if err != nil {
+		t.Fatalf("Failed to read vendored checksums: %v", err)
+	}
+	return checksums
+}
+
+func extractSupportedPlatforms(plugin Manifest) []string {
+	return plugin.platformNames()
 }
 
 func createTempDirectory(t *testing.T, pluginName string) string {
@@ -186,7 +217,7 @@ func initializeShell(t *testing.T) *Shell {
 	return &Shell{t: t, commands: make([]CommandLog, 0)}
 }
 
-func executePluginInstallation(shell *Shell, pluginName string) {
+func executePluginInstallation(shell *Shell, pluginName, version string) {
 	printStep("Setting up test environment...")
 	shell.Exec("pwd")
 
@@ -194,7 +225,32 @@ func executePluginInstallation(shell *Shell, pluginName string) {
 	shell.Exec(fmt.Sprintf("proto plugin add %s source:./%s.toml", pluginName, pluginName))
 
 	printStep("Installing plugin...")
-	shell.Exec(fmt.Sprintf("proto install %s latest", pluginName))
+	shell.Exec(fmt.Sprintf("proto install %s %s", pluginName, version))
+}
+
+// newInstalledHasher hashes artifacts proto downloads from now on.
+func newInstalledHasher(t *testing.T) ArtifactHasher {
+	tempDir, err := protoTempDir()
+	if err != nil {
+		t.Fatalf("Failed to find proto's temp directory: %v", err)
+	}
+	return installedHasher(tempDir, time.Now())
+}
+
+func verifyLockedArtifact(shell *Shell, plugin Manifest, locked LockedPlugin, hash ArtifactHasher) {
+	printStep(fmt.Sprintf("Verifying artifact against %s...", lockFileName))
+	target := currentTarget()
+	shell.Check(fmt.Sprintf("verify %s artifact for %s", lockFileName, target), func() error {
+		return verifyArtifact(hash, plugin, locked, target)
+	})
+}
+
+func verifyVendoredArtifact(shell *Shell, plugin Manifest, checksums VendoredChecksums, version string, hash ArtifactHasher) {
+	printStep("Verifying artifact against vendored checksums...")
+	target := currentTarget()
+	shell.Check(fmt.Sprintf("verify vendored checksum for %s", target), func() error {
+		return verifyVendored(hash, plugin, checksums, version, target)
+	})
 }
 
 func executeAfterInstallTests(t *testing.T, shell *Shell, afterInstall func(*testing.T, *Shell) error) {
@@ -208,16 +264,71 @@ func executeAfterInstallTests(t *testing.T, shell *Shell, afterInstall func(*tes
 
 func (s *Shell) Exec(command string) {
 	s.t.Helper()
+	if _, err := s.execute(command); err != nil {
+		s.t.Fatalf("Command failed: %s, error: %v", command, err)
+	}
+}
+
+// Expect runs a spec command and fails the test unless it exits with the
+// expected code and its output matches the expected pattern.
+func (s *Shell) Expect(command TestCommand) {
+	s.t.Helper()
+	commandLog, err := s.execute(command.Run)
+
+	exitCode := 0
+	var exitError *exec.ExitError
+	switch {
+	case errors.As(err, &exitError):
+		exitCode = exitError.ExitCode()
+	case err != nil:
+		s.t.Fatalf("Command failed: %s, error: %v", command.Run, err)
+	}
+
+	output := commandLog.Output + commandLog.Error
+	matched := command.Output == "" || regexp.MustCompile(command.Output).MatchString(output)
+	s.commands[len(s.commands)-1].Success = exitCode == command.ExitCode && matched
+
+	if exitCode != command.ExitCode {
+		s.t.Fatalf("Command %s exited with %d, want %d", command.Run, exitCode, command.ExitCode)
+	}
+	if !matched {
+		s.t.Fatalf("Command %s output does not match %q", command.Run, command.Output)
+	}
+}
+
+// Check runs a check in-process, recording it like a command so it appears
+// in failure logs, and fails the test when it returns an error.
+func (s *Shell) Check(description string, check func() error) {
+	s.t.Helper()
+	printCommand(description)
+
+	startTime := time.Now()
+	err := check()
+	commandLog := CommandLog{
+		Command:   description,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+		Success:   err == nil,
+	}
+	if err != nil {
+		commandLog.Error = err.Error()
+	}
+	s.commands = append(s.commands, commandLog)
+
+	if err != nil {
+		s.t.Fatalf("Check failed: %s, error: %v", description, err)
+	}
+}
+
+// execute runs command through sh, echoing and recording its output.
+func (s *Shell) execute(command string) (CommandLog, error) {
 	printCommand(command)
 
 	startTime := time.Now()
 	cmd := exec.Command("sh", "-c", command)
 
 	var stdout, stderr strings.Builder
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
 	cmd.Stdin = os.Stdin
-
 	cmd.Stdout = io.MultiWriter(os.Stdout, &stdout)
 	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 
@@ -233,10 +344,7 @@ func (s *Shell) Exec(command string) {
 		Success:   err == nil,
 	}
 	s.commands = append(s.commands, commandLog)
-
-	if err != nil {
-		s.t.Fatalf("Command failed: %s, error: %v", command, err)
-	}
+	return commandLog, err
 }
 
 func (s *Shell) ExecWithOutput(command string) (string, error) {
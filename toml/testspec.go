@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// testSpecDir holds one <name>.toml test spec per manifest, and fixtures in
+// <name>/. Specs live beside the manifests rather than inside them so proto
+// never sees keys it does not know.
+const testSpecDir = "testdata"
+
+// TestSpec describes how to check a plugin after proto installs it.
+type TestSpec struct {
+	// Fixtures are paths below testdata/<name>/ copied into the test's
+	// working directory before the commands run.
+	Fixtures []string      `toml:"fixtures"`
+	Commands []TestCommand `toml:"command"`
+}
+
+// TestCommand is a shell command with the exit code it must return and an
+// optional regular expression its combined output must match.
+type TestCommand struct {
+	Run      string `toml:"run"`
+	ExitCode int    `toml:"exit-code"`
+	Output   string `toml:"output"`
+}
+
+func testSpecPath(dir, name string) string {
+	return filepath.Join(dir, testSpecDir, name+".toml")
+}
+
+func readTestSpec(path string) (TestSpec, error) {
+	var spec TestSpec
+	metadata, err := toml.DecodeFile(filepath.Clean(path), &spec)
+	if err != nil {
+		return TestSpec{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return TestSpec{}, fmt.Errorf("%s: unknown keys %s", path, strings.Join(keys, ", "))
+	}
+	return spec, spec.validate()
+}
+
+func (s TestSpec) validate() error {
+	var problems []error
+	if len(s.Commands) == 0 {
+		problems = append(problems, errors.New("no [[command]] to run after installing"))
+	}
+	for i, command := range s.Commands {
+		if strings.TrimSpace(command.Run) == "" {
+			problems = append(problems, fmt.Errorf("command %d has no run", i+1))
+		}
+		if _, err := regexp.Compile(command.Output); err != nil {
+			problems = append(problems, fmt.Errorf("command %d output: %w", i+1, err))
+		}
+	}
+	for _, fixture := range s.Fixtures {
+		if filepath.IsAbs(fixture) || strings.HasPrefix(filepath.Clean(fixture), "..") {
+			problems = append(problems, fmt.Errorf("fixture %s must be relative to the plugin's fixture directory", fixture))
+		}
+	}
+	return errors.Join(problems...)
+}
+
+// findTestSpecs returns the names of the specs in dir's testdata directory.
+func findTestSpecs(dir string) ([]string, error) {
+	return tomlStems(filepath.Join(dir, testSpecDir))
+}
+
+// tomlStems returns the names of the *.toml files in dir without extension,
+// sorted.
+func tomlStems(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".toml"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// afterInstall copies the fixtures into the working directory and runs the
+// commands.
+func (s TestSpec) afterInstall(fixtureDir string) func(*testing.T, *Shell) error {
+	return func(t *testing.T, shell *Shell) error {
+		for _, fixture := range s.Fixtures {
+			if err := os.MkdirAll(filepath.Dir(fixture), 0o750); err != nil {
+				return err
+			}
+			if err := copyFile(filepath.Join(fixtureDir, fixture), fixture); err != nil {
+				return fmt.Errorf("failed to copy fixture %s: %w", fixture, err)
+			}
+		}
+		for _, command := range s.Commands {
+			shell.Expect(command)
+		}
+		return nil
+	}
+}
+
+// renderTestSpec writes the spec scaffold generates for a new tool.
+func renderTestSpec(command string) string {
+	return fmt.Sprintf("[[command]]\nrun = %s\n", tomlString(command))
+}
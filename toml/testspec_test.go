@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTestSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{
+			name: "commands and fixtures",
+			spec: "fixtures = [\"Dockerfile\"]\n\n[[command]]\nrun = \"hadolint Dockerfile\"\nexit-code = 1\noutput = 'DL\\d{4}'\n",
+		},
+		{
+			name:    "no commands",
+			spec:    "fixtures = []\n",
+			wantErr: "no [[command]] to run after installing",
+		},
+		{
+			name:    "unknown key",
+			spec:    "[[command]]\nrun = \"tool --version\"\nexit = 1\n",
+			wantErr: "unknown keys command.exit",
+		},
+		{
+			name:    "invalid pattern",
+			spec:    "[[command]]\nrun = \"tool --version\"\noutput = \"(\"\n",
+			wantErr: "command 1 output: error parsing regexp",
+		},
+		{
+			name:    "fixture outside the plugin directory",
+			spec:    "fixtures = [\"../other/file\"]\n\n[[command]]\nrun = \"tool\"\n",
+			wantErr: "fixture ../other/file must be relative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tool.toml")
+			if err := os.WriteFile(path, []byte(tt.spec), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := readTestSpec(path)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("readTestSpec() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("readTestSpec() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTestSpecs checks the specs TestPlugins uses without installing
+// anything.
+func TestTestSpecs(t *testing.T) {
+	names, err := findTestSpecs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if _, err := readTestSpec(testSpecPath(".", name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestShellExpect(t *testing.T) {
+	shell := initializeShell(t)
+	shell.Expect(TestCommand{Run: "echo 'tool 1.2.3'; exit 3", ExitCode: 3, Output: `^tool \d+\.\d+\.\d+`})
+
+	if len(shell.commands) != 1 || !shell.commands[0].Success {
+		t.Errorf("commands = %+v, want one successful command", shell.commands)
+	}
+}